@@ -10,12 +10,19 @@ import (
 	"time"
 	"trainee-assignment-backend/internal/configs"
 	"trainee-assignment-backend/internal/domain"
+	"trainee-assignment-backend/internal/infra/blob"
 	"trainee-assignment-backend/internal/infra/email"
 	"trainee-assignment-backend/internal/infra/http"
+	"trainee-assignment-backend/internal/infra/messenger"
+	"trainee-assignment-backend/internal/infra/outbox"
+	"trainee-assignment-backend/internal/infra/policy"
 	"trainee-assignment-backend/internal/infra/postgres"
+	"trainee-assignment-backend/internal/infra/purge"
 	"trainee-assignment-backend/internal/infra/redis"
 	"trainee-assignment-backend/internal/infra/security"
 	"trainee-assignment-backend/internal/infra/sms"
+	"trainee-assignment-backend/internal/infra/voice"
+	"trainee-assignment-backend/internal/infra/webauthn"
 	"trainee-assignment-backend/pkg/logging"
 )
 
@@ -44,13 +51,28 @@ func main() {
 	}
 
 	// Init Security
-	sec, err := security.NewAdapter(logger, config.Security)
+	sec, err := security.NewAdapter(logger, config.Security, db)
 	if err != nil {
 		logger.WithError(err).Fatal("Error while creating a new security adapter!")
 	}
 
 	// Init SMS
-	s := sms.NewAdapter(logger, config.SMS)
+	s, err := sms.NewAdapter(logger, config.SMS)
+	if err != nil {
+		logger.WithError(err).Fatal("Error while creating a new SMS adapter!")
+	}
+
+	// Init voice calls
+	v, err := voice.NewAdapter(logger, config.Voice)
+	if err != nil {
+		logger.WithError(err).Fatal("Error while creating a new voice adapter!")
+	}
+
+	// Init messenger
+	m, err := messenger.NewAdapter(logger, config.Messenger)
+	if err != nil {
+		logger.WithError(err).Fatal("Error while creating a new messenger adapter!")
+	}
 
 	// Init OTPStore
 	otpStore, err := redis.NewAdapter(logger, config.Redis)
@@ -61,21 +83,58 @@ func main() {
 	// Init Email adapter
 	e := email.NewAdapter(logger, config.Email)
 
+	// Init WebAuthn
+	wa := webauthn.NewAdapter(logger, config.WebAuthn)
+
+	// Init blob store
+	blobs, err := blob.NewAdapter(logger, config.Blob)
+	if err != nil {
+		logger.WithError(err).Fatal("Error while creating a new blob store adapter!")
+	}
+
+	// Init session policies
+	policies, err := policy.NewAdapter(config.Session)
+	if err != nil {
+		logger.WithError(err).Fatal("Error while creating session policies!")
+	}
+
 	// Init service
-	service := domain.NewService(logger, db, sec, otpStore, e, s)
+	service := domain.NewService(logger, db, sec, otpStore, wa, policies, blobs, config.Email.BaseBackendURL)
+
+	// Init outbox workers
+	outboxWorker := outbox.NewAdapter(logger, config.Outbox, db, e, s, v, m)
+
+	// Init the purge job of deleted accounts
+	purgeWorker := purge.NewAdapter(logger, config.Purge, db, blobs)
+
+	// Init HTTP adapter, files of the local blob store are served by it
+	if config.Blob.Driver == "local" {
+		config.HTTP.BlobDir = config.Blob.Local.Dir
+	}
 
-	// Init HTTP adapter
 	httpAdapter, err := http.NewAdapter(logger, config.HTTP, service)
 	if err != nil {
 		logger.WithError(err).Fatal("Error creating new HTTP adapter!")
 	}
 
-	shutdown := make(chan error, 1)
+	shutdown := make(chan error, 3)
 
 	go func(shutdown chan<- error) {
 		shutdown <- httpAdapter.ListenAndServe()
 	}(shutdown)
 
+	go func(shutdown chan<- error) {
+		if err := outboxWorker.Run(); err != nil {
+			shutdown <- err
+		}
+	}(shutdown)
+
+	go func(shutdown chan<- error) {
+		if err := purgeWorker.Run(); err != nil {
+			shutdown <- err
+		}
+	}(shutdown)
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
@@ -95,6 +154,15 @@ func main() {
 		logger.WithError(err).Error("Error shutting down the HTTP server!")
 	}
 
+	// Requests are done, so nothing is enqueued anymore
+	if err := outboxWorker.Shutdown(ctx); err != nil {
+		logger.WithError(err).Error("Error shutting down the outbox workers!")
+	}
+
+	if err := purgeWorker.Shutdown(ctx); err != nil {
+		logger.WithError(err).Error("Error shutting down the purge job!")
+	}
+
 	time.Sleep(time.Second)
 
 	logger.Info("The application stopped.")
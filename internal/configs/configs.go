@@ -2,25 +2,39 @@ package configs
 
 import (
 	"os"
+	"trainee-assignment-backend/internal/infra/blob"
 	"trainee-assignment-backend/internal/infra/email"
 	"trainee-assignment-backend/internal/infra/http"
+	"trainee-assignment-backend/internal/infra/messenger"
+	"trainee-assignment-backend/internal/infra/outbox"
+	"trainee-assignment-backend/internal/infra/policy"
 	"trainee-assignment-backend/internal/infra/postgres"
+	"trainee-assignment-backend/internal/infra/purge"
 	"trainee-assignment-backend/internal/infra/redis"
 	"trainee-assignment-backend/internal/infra/security"
 	"trainee-assignment-backend/internal/infra/sms"
+	"trainee-assignment-backend/internal/infra/voice"
+	"trainee-assignment-backend/internal/infra/webauthn"
 	"trainee-assignment-backend/pkg/logging"
 
 	"github.com/jessevdk/go-flags"
 )
 
 type Config struct {
-	Logger   *logging.Config  `group:"Logger args" namespace:"logger" env-namespace:"TRAINEE_ASSIGNMENT_LOGGER"`
-	Postgres *postgres.Config `group:"Postgres args" namespace:"postgres" env-namespace:"TRAINEE_ASSIGNMENT_POSTGRES"`
-	HTTP     *http.Config     `group:"HTTP args" namespace:"http" env-namespace:"TRAINEE_ASSIGNMENT_HTTP"`
-	Security *security.Config `group:"Security args" namespace:"security" env-namespace:"TRAINEE_ASSIGNMENT_SECURITY"`
-	Redis    *redis.Config    `group:"Redis args" namespace:"redis" env-namespace:"TRAINEE_ASSIGNMENT_REDIS"`
-	Email    *email.Config    `group:"Email args" namespace:"email" env-namespace:"TRAINEE_ASSIGNMENT_EMAIL"`
-	SMS      *sms.Config      `group:"SMS args" namespace:"sms" env-namespace:"TRAINEE_ASSIGNMENT_SMS"`
+	Logger    *logging.Config   `group:"Logger args" namespace:"logger" env-namespace:"TRAINEE_ASSIGNMENT_LOGGER"`
+	Postgres  *postgres.Config  `group:"Postgres args" namespace:"postgres" env-namespace:"TRAINEE_ASSIGNMENT_POSTGRES"`
+	HTTP      *http.Config      `group:"HTTP args" namespace:"http" env-namespace:"TRAINEE_ASSIGNMENT_HTTP"`
+	Security  *security.Config  `group:"Security args" namespace:"security" env-namespace:"TRAINEE_ASSIGNMENT_SECURITY"`
+	Redis     *redis.Config     `group:"Redis args" namespace:"redis" env-namespace:"TRAINEE_ASSIGNMENT_REDIS"`
+	Email     *email.Config     `group:"Email args" namespace:"email" env-namespace:"TRAINEE_ASSIGNMENT_EMAIL"`
+	SMS       *sms.Config       `group:"SMS args" namespace:"sms" env-namespace:"TRAINEE_ASSIGNMENT_SMS"`
+	Voice     *voice.Config     `group:"Voice args" namespace:"voice" env-namespace:"TRAINEE_ASSIGNMENT_VOICE"`
+	Messenger *messenger.Config `group:"Messenger args" namespace:"messenger" env-namespace:"TRAINEE_ASSIGNMENT_MESSENGER"`
+	Outbox    *outbox.Config    `group:"Outbox args" namespace:"outbox" env-namespace:"TRAINEE_ASSIGNMENT_OUTBOX"`
+	Purge     *purge.Config     `group:"Purge args" namespace:"purge" env-namespace:"TRAINEE_ASSIGNMENT_PURGE"`
+	WebAuthn  *webauthn.Config  `group:"WebAuthn args" namespace:"webauthn" env-namespace:"TRAINEE_ASSIGNMENT_WEBAUTHN"`
+	Blob      *blob.Config      `group:"Blob store args" namespace:"blob" env-namespace:"TRAINEE_ASSIGNMENT_BLOB"`
+	Session   *policy.Config    `group:"Session policy args" namespace:"session" env-namespace:"TRAINEE_ASSIGNMENT_SESSION"`
 }
 
 func Parse() (*Config, error) {
@@ -8,6 +8,8 @@ var (
 
 	// User is unauthorized
 	ErrUnauthorized = fmt.Errorf("unauthorized")
+	// Cookie authenticated request without a matching CSRF token
+	ErrInvalidCSRFToken = fmt.Errorf("invalid csrf token")
 	// Bad request
 	ErrInvalidInputData = fmt.Errorf("invalid input data")
 	// Validation Failed
@@ -16,8 +18,59 @@ var (
 	ErrUserAlreadyExists = fmt.Errorf("user already exists")
 	// Invalid registration order
 	ErrInvalidRegistrationOrder = fmt.Errorf("invalid registration order")
+	// Email is required, but isn't set
+	ErrEmailRequired = fmt.Errorf("email is required")
 	// Same email received
 	ErrSameEmail = fmt.Errorf("old and new emails are the same")
+	// Same phone received
+	ErrSamePhone = fmt.Errorf("old and new phones are the same")
+	// New phone is confirmed before the old one
+	ErrInvalidPhoneChangeOrder = fmt.Errorf("invalid phone change order")
+	// User doesn't exist
+	ErrUserNotFound = fmt.Errorf("user not found")
+	// Profile is changed since the client has seen it
+	ErrProfileVersionMismatch = fmt.Errorf("profile version mismatch")
+	// Account is restricted by the support staff
+	ErrUserSuspended       = fmt.Errorf("user is suspended")
+	ErrUserBanned          = fmt.Errorf("user is banned")
+	ErrUserPendingDeletion = fmt.Errorf("user is pending deletion")
+	// User has no permission
+	ErrForbidden = fmt.Errorf("forbidden")
+	// Sensitive operation requires a recent authentication
+	ErrStepUpRequired = fmt.Errorf("step-up authentication required")
+
+	// TOTP
+	ErrTOTPAlreadyEnabled = fmt.Errorf("totp is already enabled")
+	ErrTOTPNotEnabled     = fmt.Errorf("totp is not enabled")
+	ErrInvalidTOTPCode    = fmt.Errorf("invalid totp code")
+	// A code is accepted once, the next one is to be waited for
+	ErrTOTPCodeReused = fmt.Errorf("totp code is already used")
+	// Too many wrong codes of the user, the second factor is locked for a while
+	ErrTOTPAttemptsExceeded = fmt.Errorf("totp attempts exceeded")
+
+	// WebAuthn
+	ErrInternalWebAuthn             = fmt.Errorf("internal webauthn error")
+	ErrWebAuthnVerificationFailed   = fmt.Errorf("webauthn verification failed")
+	ErrWebAuthnCredentialNotFound   = fmt.Errorf("webauthn credential not found")
+	ErrWebAuthnCredentialRegistered = fmt.Errorf("webauthn credential is already registered")
+
+	// OAuth 2.0 errors (RFC 6749), messages are the error codes
+	ErrOAuthInvalidRequest       = fmt.Errorf("invalid_request")
+	ErrOAuthInvalidClient        = fmt.Errorf("invalid_client")
+	ErrOAuthInvalidGrant         = fmt.Errorf("invalid_grant")
+	ErrOAuthInvalidScope         = fmt.Errorf("invalid_scope")
+	ErrOAuthAccessDenied         = fmt.Errorf("access_denied")
+	ErrOAuthUnauthorizedClient   = fmt.Errorf("unauthorized_client")
+	ErrOAuthUnsupportedGrantType = fmt.Errorf("unsupported_grant_type")
+	ErrOAuthUnsupportedResponse  = fmt.Errorf("unsupported_response_type")
+	ErrOAuthInvalidRedirectURI   = fmt.Errorf("invalid redirect_uri")
+	ErrOAuthInsufficientScope    = fmt.Errorf("insufficient_scope")
+
+	// Sessions
+	ErrSessionNotFound = fmt.Errorf("session not found")
+	ErrCurrentSession  = fmt.Errorf("current session can't be revoked, use logout")
+	// Rotated refresh token is presented again, the family is revoked
+	ErrRefreshTokenReused = fmt.Errorf("refresh token reuse detected")
 
 	// Internal security module error
 	ErrInternalSecurity           = fmt.Errorf("internal security module error")
@@ -29,11 +82,31 @@ var (
 	ErrOTPSendingExceeded       = fmt.Errorf("otp sending exceeded")
 	ErrOTPRateLimitReached      = fmt.Errorf("otp rate limit reached")
 	ErrOTPAttemptsExceeded      = fmt.Errorf("otp attempts exceeded")
+	ErrOTPChannelUnavailable    = fmt.Errorf("otp channel unavailable")
 
 	ErrNonexistentOrExpiredToken = fmt.Errorf("nonexistent or expired email confirmation token")
 
 	// Internal Email
 	ErrInternalEmail         = fmt.Errorf("internal email error")
 	ErrEmailAlreadyConfirmed = fmt.Errorf("email is already confirmed")
+
+	// Internal SMS
+	ErrInternalSMS       = fmt.Errorf("internal sms error")
+	ErrSMSDeliveryFailed = fmt.Errorf("sms delivery failed")
+
+	// Internal voice calls
+	ErrInternalVoice = fmt.Errorf("internal voice error")
+
+	// Internal messenger
+	ErrInternalMessenger = fmt.Errorf("internal messenger error")
+
+	// Avatars
+	ErrInternalBlobStore = fmt.Errorf("internal blob store error")
+	ErrUnsupportedImage  = fmt.Errorf("unsupported image")
+	ErrImageTooLarge     = fmt.Errorf("image is too large")
+	ErrAvatarNotFound    = fmt.Errorf("avatar not found")
+
+	// Outbox
+	ErrOutboundMessageNotFound = fmt.Errorf("outbound message not found")
 )
 
@@ -9,15 +9,48 @@ import (
 type Service interface {
 	Register(request *RegistrationRequest) (*AuthResponse, error)
 	Login(request *LoginRequest) (*AuthResponse, error)
+	EnrollTOTP(ctx context.Context) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, code string) error
+	BeginWebAuthnRegistration(ctx context.Context) (uuid.UUID, *WebAuthnRegistrationOptions, error)
+	FinishWebAuthnRegistration(ctx context.Context, requestID uuid.UUID, a *WebAuthnAttestation) error
+	BeginWebAuthnLogin(phone string) (uuid.UUID, *WebAuthnLoginOptions, error)
+	FinishWebAuthnLogin(r *WebAuthnLoginRequest) (*AuthResponse, error)
 	GetJWT(jwtRequest *JWTRequest) (string, uuid.UUID, error)
-	ValidateRefreshToken(token string) (int, error)
+	ParseAccessToken(token string) (*AccessTokenClaims, error)
+	GetJWKS() ([]*JSONWebKey, error)
+	GetOIDCIssuer() string
+	AuthorizeOIDC(ctx context.Context, r *OIDCAuthorizationRequest) (code string, err error)
+	ExchangeOIDCCode(r *OIDCTokenRequest) (*OIDCTokenResponse, error)
+	GetOIDCUserInfo(accessToken string) (*User, []string, error)
+	Authorize(ctx context.Context, permission Permission) error
+	ValidateRefreshToken(token, userAgent, ip string) (int, error)
 	RefreshToken(ctx context.Context, fingerprint, userAgent, ip string) (*AuthResponse, error)
 	Logout(ctx context.Context, everywhere bool) error
+	StepUp(ctx context.Context, r *StepUpRequest) (*AuthResponse, error)
+	GetSessions(ctx context.Context) ([]*RefreshSession, error)
+	RevokeSession(ctx context.Context, sessionID int) error
+	RevokeSessionsByLink(token string) error
 	GetUser(ctx context.Context) (*User, error)
 	UpdateUser(ctx context.Context, r *ProfileUpdateRequest) (*User, error)
 	UpdateEmail(ctx context.Context, email string) error
+	ChangePhone(ctx context.Context, r *PhoneChangeRequest) (*AuthResponse, error)
+	DeleteAccount(ctx context.Context) error
+	ExportData(ctx context.Context) (*DataExport, error)
+	UpdateAvatar(ctx context.Context, image []byte) (*User, error)
+	DeleteAvatar(ctx context.Context) error
 	ResendConfirmationEmail(ctx context.Context) error
 	ConfirmEmail(token string) error
+	StoreDeliveryReceipt(r *DeliveryReceipt) error
+	GetOTPDeliveryStatus(requestID uuid.UUID) (*OTPDeliveryStatus, error)
+	GetOwnOTPDeliveryStatus(ctx context.Context, requestID uuid.UUID) (*OTPDeliveryStatus, error)
+
+	// Admin
+	AdminSearchUsers(r *UserSearchRequest) ([]*User, error)
+	AdminGetUser(userID int) (*User, error)
+	AdminRevokeSessions(ctx context.Context, userID int) error
+	AdminSetUserState(ctx context.Context, userID int, r *UserStateRequest) error
+	AdminGetOTPDeliveryStatus(requestID uuid.UUID) (*OTPDeliveryStatus, error)
 }
 
 type Database interface {
@@ -27,13 +60,68 @@ type Database interface {
 	GetUser(id int) (*User, error)
 	UpdateUser(id int, r *ProfileUpdateRequest) (*User, error)
 	GetUserByPhone(phone string) (*User, error)
-	CreateRefreshSession(id int, fingerprint, userAgent, ip string, expiresAt time.Time) (uuid.UUID, error)
+	IsPhoneRegistered(phone string) (bool, error)
+	ChangePhone(userID int, oldPhone, newPhone string, oldConfirmedBy OTPChannel) error
+	CreateRefreshSession(id int, clientType ClientType, fingerprint, userAgent, ip string, expiresAt time.Time) (sessionID int, refreshToken, familyID uuid.UUID, err error)
 	GetRefreshSessionByToken(token string) (*RefreshSession, error)
+	RotateRefreshSession(parent *RefreshSession, fingerprint, userAgent, ip string, expiresAt time.Time) (int, uuid.UUID, error)
+	RevokeSessionFamily(familyID uuid.UUID) error
+	UpdateSessionAuthTime(userID, sessionID int) (*RefreshSession, error)
 	RevokeSession(token string) error
-	RevokeObsoleteSessions(userID int) error
+	RevokeObsoleteSessions(userID int, clientType ClientType, keep int) (revokedFamilyIDs []uuid.UUID, err error)
 	RevokeAllSessions(userID int) error
+	GetActiveSessions(userID int) ([]*RefreshSession, error)
+	GetKnownDevices(userID int) ([]*KnownDevice, error)
+	SaveKnownDevice(d *KnownDevice) error
+	RevokeUserSession(userID, sessionID int) (familyID uuid.UUID, err error)
 	UpdateEmail(userID int, email string) error
+	UpdateAvatar(userID int, avatar *string) error
 	ConfirmEmail(emailAddress string) error
+
+	// Account deletion
+	GetUserSessions(userID int) ([]*RefreshSession, error)
+	GetUserAuditEvents(userID int) ([]*AuditEvent, error)
+	GetUserMessages(userID int) ([]*OutboundMessage, error)
+	GetUsersToPurge(limit int) ([]int, error)
+	PurgeUser(userID int, anonymize bool) error
+
+	// Roles
+	GetRolePermissions(roles []string) ([]Permission, error)
+	SearchUsers(query string, limit, offset int) ([]*User, error)
+	SetUserState(userID int, r *UserStateRequest) error
+
+	// TOTP
+	CreateTOTP(userID int, secret string) error
+	GetTOTP(userID int) (*TOTP, error)
+	ConfirmTOTP(userID int, recoveryCodeHashes []string) error
+	DeleteTOTP(userID int) error
+	UseRecoveryCode(userID int, codeHash string) error
+	UseTOTPStep(userID int, step int64) error
+
+	// JWT signing keys
+	GetSigningKeys() ([]*SigningKey, error)
+	RotateSigningKey(k *SigningKey, previousKID string, retireAt time.Time) error
+
+	// OpenID Connect
+	GetOAuthClient(clientID string) (*OAuthClient, error)
+
+	// Audit
+	CreateAuditEvent(e *AuditEvent) error
+
+	// WebAuthn
+	CreateWebAuthnCredential(c *WebAuthnCredential) error
+	GetWebAuthnCredentials(userID int) ([]*WebAuthnCredential, error)
+	GetWebAuthnCredential(credentialID []byte) (*WebAuthnCredential, error)
+	UpdateWebAuthnSignCount(id int, signCount uint32) error
+
+	// Outbox
+	EnqueueOutboundMessage(m *OutboundMessage) (int, error)
+	ClaimOutboundMessages(limit int, lease time.Duration) ([]*OutboundMessage, error)
+	MarkOutboundMessageSent(id int, provider, providerMessageID string) error
+	MarkOutboundMessageFailed(id int, lastError string, nextAttemptAt time.Time) error
+	MarkOutboundMessageDead(id int, lastError string) error
+	StoreDeliveryReceipt(r *DeliveryReceipt) error
+	GetOTPDeliveryStatus(requestID uuid.UUID, userID *int) (*OTPDeliveryStatus, error)
 }
 
 type OTPStore interface {
@@ -41,9 +129,38 @@ type OTPStore interface {
 	StoreID(requestID uuid.UUID, id int) error
 	LoadID(requestID uuid.UUID) (int, error)
 
-	Store(otpType OTPType, requestID uuid.UUID, phone, code string) error
+	Store(otpType OTPType, requestID uuid.UUID, phone, code string, channel OTPChannel) error
 	Verify(otpType OTPType, requestID uuid.UUID, phone, code string) error
 
+	// Second factor
+	StoreTOTPChallenge(requestID uuid.UUID, userID int) error
+	LoadTOTPChallenge(requestID uuid.UUID) (int, error)
+	DeleteTOTPChallenge(requestID uuid.UUID) error
+	CountTOTPAttempt(userID int) error
+	ResetTOTPAttempts(userID int) error
+
+	// OpenID Connect
+	StoreAuthorizationCode(code string, c *AuthorizationCode, ttl time.Duration) error
+	LoadAuthorizationCode(code string) (*AuthorizationCode, error)
+
+	// WebAuthn ceremonies
+	StoreWebAuthnSession(requestID uuid.UUID, session *WebAuthnSession, ttl time.Duration) error
+	LoadWebAuthnSession(requestID uuid.UUID) (*WebAuthnSession, error)
+
+	// Access token denylist
+	RevokeFamilyAccessTokens(familyID uuid.UUID, ttl time.Duration) error
+	RevokeUserAccessTokens(userID int, issuedBefore time.Time, ttl time.Duration) error
+	IsAccessTokenRevoked(c *AccessTokenClaims) (bool, error)
+
+	// Phone change
+	StorePhoneChange(requestID uuid.UUID, c *PhoneChange, ttl time.Duration) error
+	LoadPhoneChange(requestID uuid.UUID) (*PhoneChange, error)
+	DeletePhoneChange(requestID uuid.UUID) error
+
+	// "This wasn't me" links of new device notifications
+	StoreSessionsRevocation(token string, userID int, ttl time.Duration) error
+	LoadSessionsRevocation(token string) (int, error)
+
 	// Email confirmation
 	StoreEmail(token, emailAddress string) error
 	GetEmail(token string) (string, error)
@@ -51,19 +168,70 @@ type OTPStore interface {
 
 type Security interface {
 	GetRandomCode(length int) (string, error)
-	GetAccessToken(userID int, duration time.Duration) (string, error)
+	GetAccessToken(userID, sessionID int, familyID uuid.UUID, roles []string, authTime time.Time, duration time.Duration) (string, error)
+	ParseAccessToken(token string) (*AccessTokenClaims, error)
+	GetJWKS() ([]*JSONWebKey, error)
 	GetRandomToken() (string, error)
+
+	// OpenID Connect
+	GetOIDCIssuer() string
+	GetClientAccessToken(userID int, clientID string, scopes []string, duration time.Duration) (string, error)
+	GetIDToken(c *IDTokenClaims, duration time.Duration) (string, error)
+	HashClientSecret(secret string) string
+	VerifyCodeChallenge(challenge, verifier string) bool
+
+	// TOTP
+	GetTOTPSecret() (string, error)
+	GetTOTPProvisioningURI(secret, account string) string
+	ValidateTOTP(secret, code string) (step int64, ok bool)
+	GetRecoveryCode() (string, error)
+	HashRecoveryCode(code string) string
+}
+
+type WebAuthn interface {
+	NewRegistrationOptions(user *User, exclude []*WebAuthnCredential) (*WebAuthnRegistrationOptions, error)
+	VerifyRegistration(challenge []byte, a *WebAuthnAttestation) (*WebAuthnCredential, error)
+	NewLoginOptions(allow []*WebAuthnCredential) (*WebAuthnLoginOptions, error)
+	VerifyLogin(challenge []byte, credential *WebAuthnCredential, a *WebAuthnAssertion) (signCount uint32, userVerified bool, err error)
+}
+
+type SessionPolicies interface {
+	Policy(clientType ClientType) *SessionPolicy
+	// StepUpMaxAge is how recent an authentication must be for sensitive operations
+	StepUpMaxAge() time.Duration
 }
 
 type Email interface {
 	SendEmailConfirmation(address, name, token string) error
+	SendOTP(address, name, code string) error
+	SendNewDeviceLogin(address, name, device, location, link string) error
+}
+
+// BlobStore keeps public files like avatars, keys are slash-separated paths.
+type BlobStore interface {
+	Put(key, contentType string, data []byte) error
+	Delete(key string) error
+	URL(key string) string
 }
 
 type SMSSender interface {
-	SendSMS(phone, text string) error
+	SendSMS(phone, text string) (*MessageReceipt, error)
+}
+
+type VoiceCaller interface {
+	Call(phone, text string) (*MessageReceipt, error)
+}
+
+type Messenger interface {
+	SendMessage(phone, text string) (*MessageReceipt, error)
 }
 
 type Delivery interface {
 	ListenAndServe() error
 	Shutdown(ctx context.Context) error
+}
+
+type Worker interface {
+	Run() error
+	Shutdown(ctx context.Context) error
 }
\ No newline at end of file
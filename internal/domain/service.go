@@ -2,9 +2,15 @@ package domain
 
 import (
 	"context"
+	"crypto/subtle"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"trainee-assignment-backend/pkg/imaging"
+	"trainee-assignment-backend/pkg/useragent"
 )
 
 type service struct {
@@ -12,18 +18,33 @@ type service struct {
 	db       Database
 	security Security
 	otpStore OTPStore
-	email    Email
-	sms      SMSSender
+	webAuthn WebAuthn
+	policies SessionPolicies
+	blobs    BlobStore
+
+	baseBackendURL string
 }
 
-func NewService(logger logrus.FieldLogger, db Database, security Security, otpStore OTPStore, email Email, sms SMSSender) Service {
+func NewService(
+	logger logrus.FieldLogger,
+	db Database,
+	security Security,
+	otpStore OTPStore,
+	webAuthn WebAuthn,
+	policies SessionPolicies,
+	blobs BlobStore,
+	baseBackendURL string,
+) Service {
 	s := &service{
 		logger:   logger,
 		db:       db,
 		security: security,
 		otpStore: otpStore,
-		email:    email,
-		sms:      sms,
+		webAuthn: webAuthn,
+		policies: policies,
+		blobs:    blobs,
+
+		baseBackendURL: baseBackendURL,
 	}
 
 	return s
@@ -35,7 +56,14 @@ func (s *service) GetUser(ctx context.Context) (*User, error) {
 		return nil, ErrInvalidInputData
 	}
 
-	return s.db.GetUser(userID)
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setAvatarURLs(user)
+
+	return user, nil
 }
 
 func (s *service) UpdateUser(ctx context.Context, r *ProfileUpdateRequest) (*User, error) {
@@ -44,16 +72,140 @@ func (s *service) UpdateUser(ctx context.Context, r *ProfileUpdateRequest) (*Use
 		return nil, ErrInvalidInputData
 	}
 
-	return s.db.UpdateUser(userID, r)
+	user, err := s.db.UpdateUser(userID, r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setAvatarURLs(user)
+
+	return user, nil
+}
+
+// UpdateAvatar stores square variants of the image, re-encoding leaves EXIF and other metadata behind.
+// The previous avatar is deleted once the new one is set.
+func (s *service) UpdateAvatar(ctx context.Context, data []byte) (*User, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return nil, ErrInvalidInputData
+	}
+
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := imaging.Decode(data, 25000000)
+	if err != nil {
+		if err == imaging.ErrTooLarge {
+			return nil, ErrImageTooLarge
+		}
+		return nil, ErrUnsupportedImage
+	}
+
+	// Smaller variants are scaled from the larger ones, it's faster and looks the same
+	names := make([]string, 0, len(AvatarSizes))
+	for name := range AvatarSizes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return AvatarSizes[names[i]] > AvatarSizes[names[j]]
+	})
+
+	previous := user.AvatarKeys()
+	avatar := "avatars/" + strconv.Itoa(userID) + "/" + uuid.New().String()
+	user.Avatar = &avatar
+
+	keys := user.AvatarKeys()
+	for _, name := range names {
+		img = imaging.Square(img, AvatarSizes[name])
+
+		b, err := imaging.EncodeJPEG(img, 85)
+		if err != nil {
+			s.logger.WithError(err).Error("Error while encoding an avatar!")
+			return nil, ErrUnsupportedImage
+		}
+
+		if err := s.blobs.Put(keys[name], "image/jpeg", b); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.UpdateAvatar(userID, &avatar); err != nil {
+		return nil, err
+	}
+
+	s.deleteBlobs(previous)
+
+	// The update has changed the version of the profile
+	user, err = s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setAvatarURLs(user)
+
+	return user, nil
+}
+
+func (s *service) DeleteAvatar(ctx context.Context) error {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Avatar == nil {
+		return ErrAvatarNotFound
+	}
+
+	if err := s.db.UpdateAvatar(userID, nil); err != nil {
+		return err
+	}
+
+	s.deleteBlobs(user.AvatarKeys())
+
+	return nil
+}
+
+// deleteBlobs is best effort, an orphaned file is harmless.
+func (s *service) deleteBlobs(keys map[string]string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(key); err != nil {
+			s.logger.WithError(err).WithField("key", key).Warn("Error while deleting a blob!")
+		}
+	}
+}
+
+// setAvatarURLs resolves the avatar variants of the user.
+func (s *service) setAvatarURLs(user *User) {
+	keys := user.AvatarKeys()
+	if keys == nil {
+		return
+	}
+
+	user.AvatarURLs = make(map[string]string, len(keys))
+	for name, key := range keys {
+		user.AvatarURLs[name] = s.blobs.URL(key)
+	}
 }
 
 func (s *service) Register(rr *RegistrationRequest) (*AuthResponse, error) {
 	switch rr.Type {
 	case RegistrationRequestTypeStart:
-		phone := rr.Payload.(*RegistrationRequestStartPayload).Phone
+		p := rr.Payload.(*RegistrationRequestStartPayload)
 
 		// Start registration process
-		userID, err := s.db.RegisterStart(phone)
+		userID, err := s.db.RegisterStart(p.Phone)
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := s.db.GetUser(userID)
 		if err != nil {
 			return nil, err
 		}
@@ -68,11 +220,7 @@ func (s *service) Register(rr *RegistrationRequest) (*AuthResponse, error) {
 			return nil, err
 		}
 
-		if err := s.otpStore.Store(OTPTypeRegistration, requestID, phone, code); err != nil {
-			return nil, err
-		}
-
-		if err = s.sms.SendSMS(phone, "Ваш код для регистрации в Woman Club: "+code); err != nil {
+		if err := s.sendOTP(OTPTypeRegistration, requestID, user, p.Channel, code); err != nil {
 			return nil, err
 		}
 
@@ -100,11 +248,8 @@ func (s *service) Register(rr *RegistrationRequest) (*AuthResponse, error) {
 			return nil, err
 		}
 
-		if err := s.otpStore.Store(OTPTypeRegistration, rr.RequestID, user.Phone, code); err != nil {
-			return nil, err
-		}
-
-		if err = s.sms.SendSMS(user.Phone, "Ваш код для регистрации в Woman Club: "+code); err != nil {
+		channel := rr.Payload.(*RegistrationRequestResendPayload).Channel
+		if err := s.sendOTP(OTPTypeRegistration, rr.RequestID, user, channel, code); err != nil {
 			return nil, err
 		}
 
@@ -169,27 +314,7 @@ func (s *service) Register(rr *RegistrationRequest) (*AuthResponse, error) {
 			return nil, err
 		}
 
-		refreshToken, err := s.db.CreateRefreshSession(
-			userID,
-			p.Fingerprint,
-			p.UserAgent,
-			p.IP,
-			time.Now().In(time.UTC).Add(60*24*time.Hour),
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		accessToken, err := s.security.GetAccessToken(userID, 30*time.Minute)
-		if err != nil {
-			return nil, err
-		}
-
-		return &AuthResponse{
-			Status:       "ok",
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		}, nil
+		return s.createSession(userID, p.ClientType, p.Fingerprint, p.UserAgent, p.IP, p.GeoCity)
 	default:
 		return nil, ErrInvalidInputData
 	}
@@ -198,13 +323,20 @@ func (s *service) Register(rr *RegistrationRequest) (*AuthResponse, error) {
 func (s *service) Login(lr *LoginRequest) (*AuthResponse, error) {
 	switch lr.Type {
 	case LoginRequestTypeStart:
-		phone := lr.Payload.(*LoginRequestStartPayload).Phone
+		p := lr.Payload.(*LoginRequestStartPayload)
 
-		user, err := s.db.GetUserByPhone(phone)
+		user, err := s.db.GetUserByPhone(p.Phone)
 		if err != nil {
 			return nil, err
 		}
 
+		// Logging in cancels the deletion requested by the user
+		if !user.DeletionRequested() {
+			if err := user.CheckState(); err != nil {
+				return nil, err
+			}
+		}
+
 		requestID := uuid.New()
 		if err := s.otpStore.StoreID(requestID, user.ID); err != nil {
 			return nil, err
@@ -215,11 +347,7 @@ func (s *service) Login(lr *LoginRequest) (*AuthResponse, error) {
 			return nil, err
 		}
 
-		if err := s.otpStore.Store(OTPTypeLogin, requestID, phone, code); err != nil {
-			return nil, err
-		}
-
-		if err = s.sms.SendSMS(phone, "Ваш код для входа в Woman Club: "+code); err != nil {
+		if err := s.sendOTP(OTPTypeLogin, requestID, user, p.Channel, code); err != nil {
 			return nil, err
 		}
 
@@ -243,11 +371,8 @@ func (s *service) Login(lr *LoginRequest) (*AuthResponse, error) {
 			return nil, err
 		}
 
-		if err := s.otpStore.Store(OTPTypeLogin, lr.RequestID, user.Phone, code); err != nil {
-			return nil, err
-		}
-
-		if err = s.sms.SendSMS(user.Phone, "Ваш код для входа в Woman Club: "+code); err != nil {
+		channel := lr.Payload.(*LoginRequestResendPayload).Channel
+		if err := s.sendOTP(OTPTypeLogin, lr.RequestID, user, channel, code); err != nil {
 			return nil, err
 		}
 
@@ -275,212 +400,1697 @@ func (s *service) Login(lr *LoginRequest) (*AuthResponse, error) {
 			return nil, err
 		}
 
-		refreshToken, err := s.db.CreateRefreshSession(
-			userID,
-			p.Fingerprint,
-			p.UserAgent,
-			p.IP,
-			time.Now().In(time.UTC).Add(60*24*time.Hour),
-		)
+		totp, err := s.db.GetTOTP(userID)
+		if err != nil && err != ErrTOTPNotEnabled {
+			return nil, err
+		}
+
+		// The session is created only after the second factor
+		if totp != nil && totp.IsEnabled() {
+			if err := s.otpStore.StoreTOTPChallenge(lr.RequestID, userID); err != nil {
+				return nil, err
+			}
+
+			return &AuthResponse{
+				Status:    "totp_required",
+				RequestID: lr.RequestID,
+			}, nil
+		}
+
+		return s.createSession(userID, p.ClientType, p.Fingerprint, p.UserAgent, p.IP, p.GeoCity)
+	case LoginRequestTypeTOTP:
+		userID, err := s.otpStore.LoadTOTPChallenge(lr.RequestID)
 		if err != nil {
 			return nil, err
 		}
 
-		accessToken, err := s.security.GetAccessToken(userID, 30*time.Minute)
+		totp, err := s.db.GetTOTP(userID)
 		if err != nil {
 			return nil, err
 		}
 
-		return &AuthResponse{
-			Status:       "ok",
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		}, nil
+		p := lr.Payload.(*LoginRequestTOTPPayload)
+		if p.RecoveryCode != "" {
+			if err := s.db.UseRecoveryCode(userID, s.security.HashRecoveryCode(p.RecoveryCode)); err != nil {
+				return nil, err
+			}
+		} else if err := s.useTOTPCode(totp, p.TOTPCode); err != nil {
+			return nil, err
+		}
+
+		if err := s.otpStore.DeleteTOTPChallenge(lr.RequestID); err != nil {
+			return nil, err
+		}
+
+		return s.createSession(userID, p.ClientType, p.Fingerprint, p.UserAgent, p.IP, p.GeoCity)
 	default:
 		return nil, ErrInvalidInputData
 	}
 }
 
-func (s *service) GetJWT(jwtRequest *JWTRequest) (string, uuid.UUID, error) {
-	accessToken, err := s.security.GetAccessToken(jwtRequest.UserID, 30*time.Minute)
-	if err != nil {
-		return "", uuid.UUID{}, err
+func (s *service) EnrollTOTP(ctx context.Context) (*TOTPEnrollment, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return nil, ErrInvalidInputData
 	}
 
-	refreshToken, err := s.db.CreateRefreshSession(
-		jwtRequest.UserID,
-		jwtRequest.Fingerprint,
-		jwtRequest.UserAgent,
-		jwtRequest.IP,
-		time.Now().In(time.UTC).Add(60*24*time.Hour),
-	)
+	if err := s.checkStepUp(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.db.GetUser(userID)
 	if err != nil {
-		return "", uuid.UUID{}, err
+		return nil, err
 	}
 
-	return accessToken, refreshToken, nil
-}
+	totp, err := s.db.GetTOTP(userID)
+	if err != nil && err != ErrTOTPNotEnabled {
+		return nil, err
+	}
 
-func (s *service) ValidateRefreshToken(token string) (int, error) {
-	session, err := s.db.GetRefreshSessionByToken(token)
+	if totp != nil && totp.IsEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := s.security.GetTOTPSecret()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if time.Now().After(session.ExpiresAt) {
-		return 0, ErrUnauthorized
+	if err := s.db.CreateTOTP(userID, secret); err != nil {
+		return nil, err
 	}
 
-	return session.UserID, nil
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: s.security.GetTOTPProvisioningURI(secret, user.Phone),
+	}, nil
 }
 
-func (s *service) RefreshToken(ctx context.Context, fingerprint, userAgent, ip string) (*AuthResponse, error) {
-	token, ok := ctx.Value(ContextRefreshToken).(string)
+func (s *service) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
 	if !ok {
 		return nil, ErrInvalidInputData
 	}
 
-	session, err := s.db.GetRefreshSessionByToken(token)
+	totp, err := s.db.GetTOTP(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if time.Now().After(session.ExpiresAt) || fingerprint != session.Fingerprint {
-		return nil, ErrUnauthorized
+	if totp.IsEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
 	}
 
-	refreshToken, err := s.db.CreateRefreshSession(
-		session.UserID,
-		fingerprint,
-		userAgent,
-		ip,
-		time.Now().In(time.UTC).Add(60*24*time.Hour),
-	)
-	if err != nil {
+	if err := s.useTOTPCode(totp, code); err != nil {
 		return nil, err
 	}
 
-	// Revoke current token and obsolete ones
-	if err := s.db.RevokeSession(token); err != nil {
-		return nil, err
+	recoveryCodes := make([]string, 10)
+	hashes := make([]string, len(recoveryCodes))
+	for i := range recoveryCodes {
+		recoveryCode, err := s.security.GetRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		recoveryCodes[i] = recoveryCode
+		hashes[i] = s.security.HashRecoveryCode(recoveryCode)
 	}
-	if err := s.db.RevokeObsoleteSessions(session.UserID); err != nil {
+
+	if err := s.db.ConfirmTOTP(userID, hashes); err != nil {
 		return nil, err
 	}
 
-	accessToken, err := s.security.GetAccessToken(session.UserID, 30*time.Minute)
-	if err != nil {
-		return nil, err
+	return recoveryCodes, nil
+}
+
+// useTOTPCode accepts a valid code once: steps not later than the last accepted one are rejected (RFC 6238 §5.2).
+func (s *service) useTOTPCode(totp *TOTP, code string) error {
+	step, ok := s.security.ValidateTOTP(totp.Secret, code)
+	if !ok {
+		return ErrInvalidTOTPCode
 	}
 
-	return &AuthResponse{
-		Status:       "ok",
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	return s.db.UseTOTPStep(totp.UserID, step)
 }
 
-func (s *service) Logout(ctx context.Context, everywhere bool) error {
-	token, ok := ctx.Value(ContextRefreshToken).(string)
+func (s *service) DisableTOTP(ctx context.Context, code string) error {
+	userID, ok := ctx.Value(ContextUserID).(int)
 	if !ok {
 		return ErrInvalidInputData
 	}
 
-	if err := s.db.RevokeSession(token); err != nil {
+	if err := s.checkStepUp(ctx); err != nil {
 		return err
 	}
 
-	if everywhere {
-		session, err := s.db.GetRefreshSessionByToken(token)
-		if err != nil {
+	totp, err := s.db.GetTOTP(userID)
+	if err != nil {
+		return err
+	}
+
+	if !totp.IsEnabled() {
+		return ErrTOTPNotEnabled
+	}
+
+	// Recovery codes are guessed as well as the current one
+	if err := s.otpStore.CountTOTPAttempt(userID); err != nil {
+		return err
+	}
+
+	// Either a current code or one of the recovery codes is accepted
+	if err := s.useTOTPCode(totp, code); err == ErrInvalidTOTPCode {
+		if err := s.db.UseRecoveryCode(userID, s.security.HashRecoveryCode(code)); err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	}
 
-		return s.db.RevokeAllSessions(session.UserID)
+	if err := s.otpStore.ResetTOTPAttempts(userID); err != nil {
+		return err
 	}
 
-	return nil
+	return s.db.DeleteTOTP(userID)
 }
 
-func (s *service) UpdateEmail(ctx context.Context, emailAddress string) error {
+func (s *service) BeginWebAuthnRegistration(ctx context.Context) (uuid.UUID, *WebAuthnRegistrationOptions, error) {
 	userID, ok := ctx.Value(ContextUserID).(int)
 	if !ok {
-		return ErrInvalidInputData
-	}
-
-	user, err := s.db.GetUser(userID)
-	if err != nil {
-		return err
+		return uuid.Nil, nil, ErrInvalidInputData
 	}
 
-	if user.FirstName == nil {
-		return ErrInvalidInputData
+	if err := s.checkStepUp(ctx); err != nil {
+		return uuid.Nil, nil, err
 	}
 
-	if err := s.db.UpdateEmail(userID, emailAddress); err != nil {
-		return err
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return uuid.Nil, nil, err
 	}
 
-	token, err := s.security.GetRandomToken()
+	// Authenticators refuse to create a second credential for the same account
+	credentials, err := s.db.GetWebAuthnCredentials(userID)
 	if err != nil {
-		return err
+		return uuid.Nil, nil, err
 	}
 
-	if err := s.otpStore.StoreEmail(token, emailAddress); err != nil {
-		return err
+	options, err := s.webAuthn.NewRegistrationOptions(user, credentials)
+	if err != nil {
+		return uuid.Nil, nil, err
 	}
 
-	if err := s.email.SendEmailConfirmation(emailAddress, *user.FirstName, token); err != nil {
-		return err
+	requestID := uuid.New()
+	if err := s.otpStore.StoreWebAuthnSession(requestID, &WebAuthnSession{
+		Challenge: options.Challenge,
+		UserID:    userID,
+	}, options.Timeout); err != nil {
+		return uuid.Nil, nil, err
 	}
 
-	return nil
+	return requestID, options, nil
 }
 
-func (s *service) ResendConfirmationEmail(ctx context.Context) error {
+func (s *service) FinishWebAuthnRegistration(ctx context.Context, requestID uuid.UUID, a *WebAuthnAttestation) error {
 	userID, ok := ctx.Value(ContextUserID).(int)
 	if !ok {
 		return ErrInvalidInputData
 	}
 
-	user, err := s.db.GetUser(userID)
+	session, err := s.otpStore.LoadWebAuthnSession(requestID)
 	if err != nil {
 		return err
 	}
 
-	if user.FirstName == nil || user.Email == nil {
-		return ErrInvalidInputData
-	}
-
-	if user.Status.IsEmailConfirmed() {
-		return ErrEmailAlreadyConfirmed
+	if session.UserID != userID {
+		return ErrWebAuthnVerificationFailed
 	}
 
-	token, err := s.security.GetRandomToken()
+	credential, err := s.webAuthn.VerifyRegistration(session.Challenge, a)
 	if err != nil {
 		return err
 	}
 
-	if err := s.otpStore.StoreEmail(token, *user.Email); err != nil {
-		return err
+	credential.UserID = userID
+
+	return s.db.CreateWebAuthnCredential(credential)
+}
+
+// BeginWebAuthnLogin limits the allowed credentials to the ones of the user if the phone is given,
+// otherwise an authenticator offers its discoverable credentials.
+func (s *service) BeginWebAuthnLogin(phone string) (uuid.UUID, *WebAuthnLoginOptions, error) {
+	var (
+		userID      int
+		credentials []*WebAuthnCredential
+	)
+
+	if phone != "" {
+		user, err := s.db.GetUserByPhone(phone)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+
+		credentials, err = s.db.GetWebAuthnCredentials(user.ID)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+
+		if len(credentials) == 0 {
+			return uuid.Nil, nil, ErrWebAuthnCredentialNotFound
+		}
+
+		userID = user.ID
 	}
 
-	if err := s.email.SendEmailConfirmation(*user.Email, *user.FirstName, token); err != nil {
-		return err
+	options, err := s.webAuthn.NewLoginOptions(credentials)
+	if err != nil {
+		return uuid.Nil, nil, err
 	}
 
-	return nil
+	requestID := uuid.New()
+	if err := s.otpStore.StoreWebAuthnSession(requestID, &WebAuthnSession{
+		Challenge: options.Challenge,
+		UserID:    userID,
+	}, options.Timeout); err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return requestID, options, nil
 }
 
-func (s *service) ConfirmEmail(token string) error {
-	emailAddress, err := s.otpStore.GetEmail(token)
+func (s *service) FinishWebAuthnLogin(r *WebAuthnLoginRequest) (*AuthResponse, error) {
+	session, err := s.otpStore.LoadWebAuthnSession(r.RequestID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := s.db.ConfirmEmail(emailAddress); err != nil {
-		return err
+	credential, err := s.db.GetWebAuthnCredential(r.Assertion.CredentialID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	if session.UserID != 0 && session.UserID != credential.UserID {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+
+	signCount, userVerified, err := s.webAuthn.VerifyLogin(session.Challenge, credential, r.Assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.UpdateWebAuthnSignCount(credential.ID, signCount); err != nil {
+		return nil, err
+	}
+
+	// Without a PIN or biometrics the passkey is a single factor, TOTP is still asked for
+	if !userVerified {
+		totp, err := s.db.GetTOTP(credential.UserID)
+		if err != nil && err != ErrTOTPNotEnabled {
+			return nil, err
+		}
+
+		if totp != nil && totp.IsEnabled() {
+			if err := s.otpStore.StoreTOTPChallenge(r.RequestID, credential.UserID); err != nil {
+				return nil, err
+			}
+
+			return &AuthResponse{
+				Status:    "totp_required",
+				RequestID: r.RequestID,
+			}, nil
+		}
+	}
+
+	return s.createSession(credential.UserID, r.ClientType, r.Fingerprint, r.UserAgent, r.IP, r.GeoCity)
+}
+
+// GetJWT issues tokens of a user to a service client within the users granted by its scopes.
+// Every issuance is audited.
+func (s *service) GetJWT(r *JWTRequest) (string, uuid.UUID, error) {
+	client, err := s.db.GetOAuthClient(r.ClientID)
+	if err != nil {
+		return "", uuid.UUID{}, err
+	}
+
+	authenticated, method := false, ""
+	switch {
+	case r.TLSSubject != "" && client.TLSClientAuthSubject != nil:
+		authenticated, method = r.TLSSubject == *client.TLSClientAuthSubject, "tls_client_auth"
+	case r.ClientSecret != "" && client.IsConfidential():
+		authenticated, method = s.checkClientSecret(client, r.ClientSecret), "client_secret_basic"
+	}
+
+	if !authenticated {
+		return "", uuid.UUID{}, ErrOAuthInvalidClient
+	}
+
+	if !client.HasGrantType(GrantTypeClientCredentials) {
+		return "", uuid.UUID{}, ErrOAuthUnauthorizedClient
+	}
+
+	if !client.HasScope(ScopeAllUsers) && !client.HasScope(UserScope(r.UserID)) {
+		return "", uuid.UUID{}, ErrOAuthInvalidScope
+	}
+
+	resp, err := s.createSession(r.UserID, ClientTypeService, r.Fingerprint, r.UserAgent, r.IP, "")
+	if err != nil {
+		return "", uuid.UUID{}, err
+	}
+
+	if err := s.db.CreateAuditEvent(&AuditEvent{
+		Type:      AuditEventTypeJWTIssued,
+		UserID:    &r.UserID,
+		ClientID:  &client.ClientID,
+		IP:        r.IP,
+		UserAgent: r.UserAgent,
+		Details:   map[string]string{"auth_method": method},
+	}); err != nil {
+		return "", uuid.UUID{}, err
+	}
+
+	return resp.AccessToken, resp.RefreshToken, nil
+}
+
+// ParseAccessToken verifies the token, the denylist and the account state, so a logged out
+// or restricted user is cut off before the token expires.
+func (s *service) ParseAccessToken(token string) (*AccessTokenClaims, error) {
+	claims, err := s.security.ParseAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.otpStore.IsAccessTokenRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrUnauthorized
+	}
+
+	if _, err := s.getActiveUser(claims.UserID); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// getActiveUser returns the user unless the account is restricted.
+func (s *service) getActiveUser(userID int) (*User, error) {
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := user.CheckState(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// checkClientSecret compares hashes in constant time not to leak how much of the secret is right.
+func (s *service) checkClientSecret(client *OAuthClient, secret string) bool {
+	return client.SecretHash != nil &&
+		subtle.ConstantTimeCompare([]byte(s.security.HashClientSecret(secret)), []byte(*client.SecretHash)) == 1
+}
+
+func (s *service) GetJWKS() ([]*JSONWebKey, error) {
+	return s.security.GetJWKS()
+}
+
+func (s *service) GetOIDCIssuer() string {
+	return s.security.GetOIDCIssuer()
+}
+
+// AuthorizeOIDC issues an authorization code to a client for the signed in user. Errors other than
+// ErrOAuthInvalidClient and ErrOAuthInvalidRedirectURI are to be returned to the redirect URI.
+func (s *service) AuthorizeOIDC(ctx context.Context, r *OIDCAuthorizationRequest) (string, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return "", ErrInvalidInputData
+	}
+
+	client, err := s.db.GetOAuthClient(r.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	if !client.HasRedirectURI(r.RedirectURI) {
+		return "", ErrOAuthInvalidRedirectURI
+	}
+
+	if !client.HasGrantType(GrantTypeAuthorizationCode) {
+		return "", ErrOAuthUnauthorizedClient
+	}
+
+	if r.ResponseType != "code" {
+		return "", ErrOAuthUnsupportedResponse
+	}
+
+	// PKCE is required for every client
+	if r.CodeChallenge == "" || r.CodeChallengeMethod != "S256" {
+		return "", ErrOAuthInvalidRequest
+	}
+
+	openID := false
+	for _, scope := range r.Scopes {
+		if !client.HasScope(scope) {
+			return "", ErrOAuthInvalidScope
+		}
+
+		if scope == OIDCScopeOpenID {
+			openID = true
+		}
+	}
+
+	if !openID {
+		return "", ErrOAuthInvalidScope
+	}
+
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return "", err
+	}
+
+	if !user.Status.IsFinished() {
+		return "", ErrOAuthAccessDenied
+	}
+
+	code, err := s.security.GetRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.otpStore.StoreAuthorizationCode(code, &AuthorizationCode{
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   r.RedirectURI,
+		Scopes:        r.Scopes,
+		Nonce:         r.Nonce,
+		CodeChallenge: r.CodeChallenge,
+	}, time.Minute); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+func (s *service) ExchangeOIDCCode(r *OIDCTokenRequest) (*OIDCTokenResponse, error) {
+	if r.GrantType != GrantTypeAuthorizationCode {
+		return nil, ErrOAuthUnsupportedGrantType
+	}
+
+	client, err := s.db.GetOAuthClient(r.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.IsConfidential() && !s.checkClientSecret(client, r.ClientSecret) {
+		return nil, ErrOAuthInvalidClient
+	}
+
+	if !client.HasGrantType(GrantTypeAuthorizationCode) {
+		return nil, ErrOAuthUnauthorizedClient
+	}
+
+	code, err := s.otpStore.LoadAuthorizationCode(r.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != r.RedirectURI {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	if !s.security.VerifyCodeChallenge(code.CodeChallenge, r.CodeVerifier) {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	// The user may be blocked or deleted after the code is issued
+	if _, err := s.getActiveUser(code.UserID); err != nil {
+		return nil, err
+	}
+
+	expiresIn := 30 * time.Minute
+
+	accessToken, err := s.security.GetClientAccessToken(code.UserID, client.ClientID, code.Scopes, expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := s.security.GetIDToken(&IDTokenClaims{
+		UserID:   code.UserID,
+		ClientID: client.ClientID,
+		Nonce:    code.Nonce,
+	}, expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCTokenResponse{
+		AccessToken: accessToken,
+		IDToken:     idToken,
+		ExpiresIn:   expiresIn,
+		Scopes:      code.Scopes,
+	}, nil
+}
+
+// GetOIDCUserInfo returns the user of a client access token and the granted scopes.
+func (s *service) GetOIDCUserInfo(accessToken string) (*User, []string, error) {
+	// Revoked tokens and restricted users get no claims either
+	claims, err := s.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	openID := false
+	for _, scope := range claims.Scopes {
+		if scope == OIDCScopeOpenID {
+			openID = true
+		}
+	}
+
+	if claims.ClientID == "" || !openID {
+		return nil, nil, ErrOAuthInsufficientScope
+	}
+
+	user, err := s.db.GetUser(claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.setAvatarURLs(user)
+
+	return user, claims.Scopes, nil
+}
+
+func (s *service) ValidateRefreshToken(token, userAgent, ip string) (int, error) {
+	session, err := s.db.GetRefreshSessionByToken(token)
+	if err != nil {
+		return 0, err
+	}
+
+	if session.RotatedAt != nil {
+		return 0, s.revokeReusedSession(session, userAgent, ip)
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return 0, ErrUnauthorized
+	}
+
+	if _, err := s.getActiveUser(session.UserID); err != nil {
+		return 0, err
+	}
+
+	return session.UserID, nil
+}
+
+func (s *service) RefreshToken(ctx context.Context, fingerprint, userAgent, ip string) (*AuthResponse, error) {
+	token, ok := ctx.Value(ContextRefreshToken).(string)
+	if !ok {
+		return nil, ErrInvalidInputData
+	}
+
+	session, err := s.db.GetRefreshSessionByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.RotatedAt != nil {
+		return nil, s.revokeReusedSession(session, userAgent, ip)
+	}
+
+	if time.Now().After(session.ExpiresAt) || fingerprint != session.Fingerprint {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.getActiveUser(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sliding sessions are prolonged, absolute ones keep the expiry of the login
+	policy := s.policies.Policy(session.ClientType)
+	expiresAt := session.ExpiresAt
+	if policy.Sliding {
+		expiresAt = time.Now().In(time.UTC).Add(policy.RefreshTokenTTL)
+	}
+
+	// Current token is rotated by the new one of the same family
+	sessionID, refreshToken, err := s.db.RotateRefreshSession(
+		session,
+		fingerprint,
+		userAgent,
+		ip,
+		expiresAt,
+	)
+	if err == ErrRefreshTokenReused {
+		// Concurrent rotation of the same token
+		return nil, s.revokeReusedSession(session, userAgent, ip)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.security.GetAccessToken(
+		session.UserID,
+		sessionID,
+		session.FamilyID,
+		user.Roles,
+		session.AuthTime,
+		policy.AccessTokenTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Status:           "ok",
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: expiresAt,
+	}, nil
+}
+
+// revokeReusedSession revokes the family of a replayed refresh token: either the owner or a thief
+// holds a stolen copy, so neither may keep the session.
+func (s *service) revokeReusedSession(session *RefreshSession, userAgent, ip string) error {
+	s.logger.WithFields(logrus.Fields{
+		"user_id":   session.UserID,
+		"family_id": session.FamilyID,
+		"ip":        ip,
+	}).Warn("Refresh token reuse detected, revoking the family!")
+
+	if err := s.db.RevokeSessionFamily(session.FamilyID); err != nil {
+		return err
+	}
+
+	if err := s.otpStore.RevokeFamilyAccessTokens(session.FamilyID, s.maxAccessTokenTTL()); err != nil {
+		return err
+	}
+
+	if err := s.db.CreateAuditEvent(&AuditEvent{
+		Type:      AuditEventTypeRefreshReused,
+		UserID:    &session.UserID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]string{
+			"family_id":  session.FamilyID.String(),
+			"session_id": strconv.Itoa(session.ID),
+		},
+	}); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// StepUp proves the presence of the user with a fresh code, the session gets a new authentication time
+// and an access token carrying it.
+func (s *service) StepUp(ctx context.Context, r *StepUpRequest) (*AuthResponse, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return nil, ErrInvalidInputData
+	}
+
+	sessionID, ok := ctx.Value(ContextSessionID).(int)
+	if !ok || sessionID == 0 {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Type {
+	case StepUpRequestTypeStart:
+		code, err := s.security.GetRandomCode(6)
+		if err != nil {
+			return nil, err
+		}
+
+		requestID := uuid.New()
+		if err := s.sendOTP(OTPTypeStepUp, requestID, user, r.Channel, code); err != nil {
+			return nil, err
+		}
+
+		return &AuthResponse{
+			Status:    "ok",
+			RequestID: requestID,
+		}, nil
+	case StepUpRequestTypeConfirm:
+		// Codes are bound to the phone, a code sent to another user doesn't verify
+		if err := s.otpStore.Verify(OTPTypeStepUp, r.RequestID, user.Phone, r.Code); err != nil {
+			return nil, err
+		}
+	case StepUpRequestTypeTOTP:
+		totp, err := s.db.GetTOTP(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !totp.IsEnabled() {
+			return nil, ErrTOTPNotEnabled
+		}
+
+		// A stolen access token mustn't be enough to guess the code
+		if err := s.otpStore.CountTOTPAttempt(userID); err != nil {
+			return nil, err
+		}
+
+		if err := s.useTOTPCode(totp, r.Code); err != nil {
+			return nil, err
+		}
+
+		if err := s.otpStore.ResetTOTPAttempts(userID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidInputData
+	}
+
+	session, err := s.db.UpdateSessionAuthTime(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.security.GetAccessToken(
+		userID,
+		sessionID,
+		session.FamilyID,
+		user.Roles,
+		session.AuthTime,
+		s.policies.Policy(session.ClientType).AccessTokenTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Status:      "ok",
+		AccessToken: accessToken,
+	}, nil
+}
+
+// checkStepUp requires the access token of the request to be issued after a recent authentication.
+func (s *service) checkStepUp(ctx context.Context) error {
+	authTime, _ := ctx.Value(ContextAuthTime).(time.Time)
+	if authTime.IsZero() || time.Since(authTime) > s.policies.StepUpMaxAge() {
+		return ErrStepUpRequired
+	}
+
+	return nil
+}
+
+func (s *service) Logout(ctx context.Context, everywhere bool) error {
+	token, ok := ctx.Value(ContextRefreshToken).(string)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	session, err := s.db.GetRefreshSessionByToken(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.RevokeSession(token); err != nil {
+		return err
+	}
+
+	if everywhere {
+		if err := s.db.RevokeAllSessions(session.UserID); err != nil {
+			return err
+		}
+
+		return s.revokeAccessTokens(session.UserID)
+	}
+
+	// Tokens issued before the last rotation carry IDs of the rotated sessions
+	return s.otpStore.RevokeFamilyAccessTokens(session.FamilyID, s.maxAccessTokenTTL())
+}
+
+// revokeAccessTokens denies access tokens issued to the user so far.
+func (s *service) revokeAccessTokens(userID int) error {
+	return s.otpStore.RevokeUserAccessTokens(userID, time.Now().In(time.UTC), s.maxAccessTokenTTL())
+}
+
+// maxAccessTokenTTL is how long revocations are kept in the denylist, tokens of any client type expire by then.
+func (s *service) maxAccessTokenTTL() time.Duration {
+	var ttl time.Duration
+	for _, t := range ClientTypes {
+		if p := s.policies.Policy(t); p.AccessTokenTTL > ttl {
+			ttl = p.AccessTokenTTL
+		}
+	}
+
+	return ttl
+}
+
+// GetSessions lists active sessions of the user, the session of the request is marked.
+func (s *service) GetSessions(ctx context.Context) ([]*RefreshSession, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return nil, ErrInvalidInputData
+	}
+
+	token, _ := ctx.Value(ContextRefreshToken).(string)
+
+	sessions, err := s.db.GetActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.RefreshToken == token
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs out another device of the user.
+func (s *service) RevokeSession(ctx context.Context, sessionID int) error {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	token, ok := ctx.Value(ContextRefreshToken).(string)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	current, err := s.db.GetRefreshSessionByToken(token)
+	if err != nil {
+		return err
+	}
+
+	if current.ID == sessionID {
+		return ErrCurrentSession
+	}
+
+	familyID, err := s.db.RevokeUserSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	return s.otpStore.RevokeFamilyAccessTokens(familyID, s.maxAccessTokenTTL())
+}
+
+func (s *service) UpdateEmail(ctx context.Context, emailAddress string) error {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	if err := s.checkStepUp(ctx); err != nil {
+		return err
+	}
+
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.FirstName == nil {
+		return ErrInvalidInputData
+	}
+
+	if err := s.db.UpdateEmail(userID, emailAddress); err != nil {
+		return err
+	}
+
+	token, err := s.security.GetRandomToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.otpStore.StoreEmail(token, emailAddress); err != nil {
+		return err
+	}
+
+	if err := s.enqueueEmailConfirmation(user.ID, emailAddress, *user.FirstName, token); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ChangePhone moves the user to a new phone, both numbers are verified. The old one may be replaced
+// by the confirmed email when the SIM is lost. All sessions are revoked on completion.
+func (s *service) ChangePhone(ctx context.Context, r *PhoneChangeRequest) (*AuthResponse, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return nil, ErrInvalidInputData
+	}
+
+	user, err := s.getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := 15 * time.Minute
+
+	if r.Type == PhoneChangeRequestTypeStart {
+		if err := s.checkStepUp(ctx); err != nil {
+			return nil, err
+		}
+
+		p := r.Payload.(*PhoneChangeRequestStartPayload)
+		if p.Phone == user.Phone {
+			return nil, ErrSamePhone
+		}
+
+		registered, err := s.db.IsPhoneRegistered(p.Phone)
+		if err != nil {
+			return nil, err
+		}
+		if registered {
+			return nil, ErrUserAlreadyExists
+		}
+
+		channel := p.Channel
+		if channel == "" {
+			channel = OTPChannelSMS
+		}
+
+		requestID := uuid.New()
+		change := &PhoneChange{
+			UserID:     userID,
+			OldPhone:   user.Phone,
+			NewPhone:   p.Phone,
+			OldChannel: channel,
+		}
+		if err := s.sendPhoneChangeOTP(requestID, user, change, channel); err != nil {
+			return nil, err
+		}
+
+		if err := s.otpStore.StorePhoneChange(requestID, change, ttl); err != nil {
+			return nil, err
+		}
+
+		return &AuthResponse{
+			Status:    "ok",
+			RequestID: requestID,
+		}, nil
+	}
+
+	change, err := s.otpStore.LoadPhoneChange(r.RequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests of other users are told apart from expired ones
+	if change.UserID != userID || change.OldPhone != user.Phone {
+		return nil, ErrNonexistentOrExpiredCode
+	}
+
+	switch r.Type {
+	case PhoneChangeRequestTypeResend:
+		channel := r.Payload.(*PhoneChangeRequestResendPayload).Channel
+		if channel == "" {
+			channel = OTPChannelSMS
+		}
+		if !change.OldConfirmed {
+			change.OldChannel = channel
+		}
+
+		if err := s.sendPhoneChangeOTP(r.RequestID, user, change, channel); err != nil {
+			return nil, err
+		}
+
+		if err := s.otpStore.StorePhoneChange(r.RequestID, change, ttl); err != nil {
+			return nil, err
+		}
+	case PhoneChangeRequestTypeConfirmOld:
+		if change.OldConfirmed {
+			return nil, ErrInvalidPhoneChangeOrder
+		}
+
+		code := r.Payload.(*PhoneChangeRequestConfirmPayload).Code
+		if err := s.otpStore.Verify(OTPTypePhoneOld, r.RequestID, change.OldPhone, code); err != nil {
+			return nil, err
+		}
+
+		change.OldConfirmed = true
+		if err := s.sendPhoneChangeOTP(r.RequestID, user, change, OTPChannelSMS); err != nil {
+			return nil, err
+		}
+
+		if err := s.otpStore.StorePhoneChange(r.RequestID, change, ttl); err != nil {
+			return nil, err
+		}
+	case PhoneChangeRequestTypeConfirmNew:
+		if !change.OldConfirmed {
+			return nil, ErrInvalidPhoneChangeOrder
+		}
+
+		code := r.Payload.(*PhoneChangeRequestConfirmPayload).Code
+		if err := s.otpStore.Verify(OTPTypePhoneNew, r.RequestID, change.NewPhone, code); err != nil {
+			return nil, err
+		}
+
+		if err := s.db.ChangePhone(userID, change.OldPhone, change.NewPhone, change.OldChannel); err != nil {
+			return nil, err
+		}
+
+		if err := s.otpStore.DeletePhoneChange(r.RequestID); err != nil {
+			return nil, err
+		}
+
+		// The old number may be in the wrong hands, so are the sessions
+		if err := s.db.RevokeAllSessions(userID); err != nil {
+			return nil, err
+		}
+
+		if err := s.revokeAccessTokens(userID); err != nil {
+			return nil, err
+		}
+
+		if err := s.db.CreateAuditEvent(&AuditEvent{
+			Type:   AuditEventTypePhoneChanged,
+			UserID: &userID,
+			Details: map[string]string{
+				"old_phone":        change.OldPhone,
+				"new_phone":        change.NewPhone,
+				"old_confirmed_by": string(change.OldChannel),
+			},
+		}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidInputData
+	}
+
+	return &AuthResponse{
+		Status: "ok",
+	}, nil
+}
+
+// sendPhoneChangeOTP sends a code to the old number until it's confirmed, to the new one afterwards.
+// Only the old number may be replaced by the email, the new one must prove itself.
+func (s *service) sendPhoneChangeOTP(requestID uuid.UUID, user *User, change *PhoneChange, channel OTPChannel) error {
+	code, err := s.security.GetRandomCode(6)
+	if err != nil {
+		return err
+	}
+
+	if !change.OldConfirmed {
+		return s.sendOTP(OTPTypePhoneOld, requestID, user, channel, code)
+	}
+
+	if channel == OTPChannelEmail {
+		return ErrOTPChannelUnavailable
+	}
+
+	recipient := *user
+	recipient.Phone = change.NewPhone
+
+	return s.sendOTP(OTPTypePhoneNew, requestID, &recipient, channel, code)
+}
+
+// DeleteAccount logs the user out everywhere and schedules the deletion, logging in within 30 days cancels it.
+func (s *service) DeleteAccount(ctx context.Context) error {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	if err := s.checkStepUp(ctx); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().In(time.UTC).Add(30 * 24 * time.Hour)
+	if err := s.db.SetUserState(userID, &UserStateRequest{
+		State:     AccountStatePendingDeletion,
+		Reason:    DeletionReasonUserRequest,
+		ExpiresAt: &expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.db.RevokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	if err := s.revokeAccessTokens(userID); err != nil {
+		return err
+	}
+
+	return s.db.CreateAuditEvent(&AuditEvent{
+		Type:    AuditEventTypeDeletionRequested,
+		UserID:  &userID,
+		Details: map[string]string{"purge_at": expiresAt.Format(time.RFC3339)},
+	})
+}
+
+// cancelDeletion makes the account active again on a login within the grace period.
+func (s *service) cancelDeletion(user *User) error {
+	if err := s.db.SetUserState(user.ID, &UserStateRequest{State: AccountStateActive}); err != nil {
+		return err
+	}
+
+	user.Status &^= 0b10000000
+	user.StatusReason = nil
+	user.StatusExpiresAt = nil
+
+	return s.db.CreateAuditEvent(&AuditEvent{
+		Type:   AuditEventTypeDeletionCancelled,
+		UserID: &user.ID,
+	})
+}
+
+// ExportData collects everything kept about the user.
+func (s *service) ExportData(ctx context.Context) (*DataExport, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return nil, ErrInvalidInputData
+	}
+
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setAvatarURLs(user)
+
+	sessions, err := s.db.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := s.db.GetKnownDevices(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.db.GetUserAuditEvents(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.db.GetUserMessages(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataExport{
+		User:         user,
+		Sessions:     sessions,
+		KnownDevices: devices,
+		AuditEvents:  events,
+		Messages:     messages,
+	}, nil
+}
+
+func (s *service) ResendConfirmationEmail(ctx context.Context) error {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.FirstName == nil || user.Email == nil {
+		return ErrInvalidInputData
+	}
+
+	if user.Status.IsEmailConfirmed() {
+		return ErrEmailAlreadyConfirmed
+	}
+
+	token, err := s.security.GetRandomToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.otpStore.StoreEmail(token, *user.Email); err != nil {
+		return err
+	}
+
+	if err := s.enqueueEmailConfirmation(user.ID, *user.Email, *user.FirstName, token); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ConfirmEmail(token string) error {
+	emailAddress, err := s.otpStore.GetEmail(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.ConfirmEmail(emailAddress); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) StoreDeliveryReceipt(r *DeliveryReceipt) error {
+	return s.db.StoreDeliveryReceipt(r)
+}
+
+// GetOTPDeliveryStatus shows the code of a pending login or registration to the one who waits for it.
+func (s *service) GetOTPDeliveryStatus(requestID uuid.UUID) (*OTPDeliveryStatus, error) {
+	userID, err := s.otpStore.LoadID(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getUserOTPDeliveryStatus(requestID, userID)
+}
+
+// GetOwnOTPDeliveryStatus shows the code of a step-up or a phone change to the current user.
+func (s *service) GetOwnOTPDeliveryStatus(ctx context.Context, requestID uuid.UUID) (*OTPDeliveryStatus, error) {
+	userID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return nil, ErrInvalidInputData
+	}
+
+	return s.getUserOTPDeliveryStatus(requestID, userID)
+}
+
+// getUserOTPDeliveryStatus hides the provider and its error codes, they are for the support only.
+func (s *service) getUserOTPDeliveryStatus(requestID uuid.UUID, userID int) (*OTPDeliveryStatus, error) {
+	status, err := s.db.GetOTPDeliveryStatus(requestID, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Provider = nil
+	status.ErrorCode = nil
+
+	return status, nil
+}
+
+// createSession logs the user in, the oldest sessions of the client type beyond the policy limit are revoked.
+func (s *service) createSession(
+	userID int,
+	clientType ClientType,
+	fingerprint, userAgent, ip, geoCity string,
+) (*AuthResponse, error) {
+	if clientType == "" {
+		clientType = ClientTypeWeb
+	}
+
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Service clients act on behalf of users, only the user may cancel the deletion
+	if user.DeletionRequested() && clientType != ClientTypeService {
+		if err := s.cancelDeletion(user); err != nil {
+			return nil, err
+		}
+	} else if err := user.CheckState(); err != nil {
+		return nil, err
+	}
+	policy := s.policies.Policy(clientType)
+	expiresAt := time.Now().In(time.UTC).Add(policy.RefreshTokenTTL)
+
+	sessionID, refreshToken, familyID, err := s.db.CreateRefreshSession(
+		userID,
+		clientType,
+		fingerprint,
+		userAgent,
+		ip,
+		expiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revokedFamilyIDs, err := s.db.RevokeObsoleteSessions(userID, clientType, policy.MaxSessions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range revokedFamilyIDs {
+		if err := s.otpStore.RevokeFamilyAccessTokens(id, s.maxAccessTokenTTL()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Service clients act on behalf of users, they aren't their devices
+	if clientType != ClientTypeService {
+		s.checkDevice(user, fingerprint, userAgent, ip, geoCity)
+	}
+
+	accessToken, err := s.security.GetAccessToken(
+		userID,
+		sessionID,
+		familyID,
+		user.Roles,
+		time.Now().In(time.UTC),
+		policy.AccessTokenTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Status:           "ok",
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: expiresAt,
+	}, nil
+}
+
+// checkDevice remembers the device and notifies the user of a login from an unseen device, city or IP.
+// The first device of a user is not notified. Failures are logged only, the user is logged in already.
+func (s *service) checkDevice(user *User, fingerprint, userAgent, ip, geoCity string) {
+	logger := s.logger.WithField("user_id", user.ID)
+
+	devices, err := s.db.GetKnownDevices(user.ID)
+	if err != nil {
+		logger.WithError(err).Error("Error while checking a device!")
+		return
+	}
+
+	newDevice, newIP, newCity := true, true, geoCity != ""
+	for _, d := range devices {
+		if d.Fingerprint == fingerprint {
+			newDevice = false
+		}
+		if d.IP == ip {
+			newIP = false
+		}
+		if d.City == geoCity {
+			newCity = false
+		}
+	}
+
+	if err := s.db.SaveKnownDevice(&KnownDevice{
+		UserID:      user.ID,
+		Fingerprint: fingerprint,
+		UserAgent:   userAgent,
+		IP:          ip,
+		City:        geoCity,
+	}); err != nil {
+		logger.WithError(err).Error("Error while saving a device!")
+		return
+	}
+
+	if len(devices) == 0 || !newDevice && !newIP && !newCity {
+		return
+	}
+
+	if err := s.db.CreateAuditEvent(&AuditEvent{
+		Type:      AuditEventTypeNewDeviceLogin,
+		UserID:    &user.ID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]string{
+			"new_device": strconv.FormatBool(newDevice),
+			"new_ip":     strconv.FormatBool(newIP),
+			"new_city":   strconv.FormatBool(newCity),
+		},
+	}); err != nil {
+		logger.WithError(err).Error("Error while auditing a new device login!")
+	}
+
+	if err := s.notifyNewDevice(user, userAgent, ip, geoCity); err != nil {
+		logger.WithError(err).Error("Error while notifying of a new device login!")
+	}
+}
+
+// notifyNewDevice sends a "this wasn't me" link revoking all sessions of the user.
+// The link goes to the confirmed email, to the phone otherwise.
+func (s *service) notifyNewDevice(user *User, userAgent, ip, geoCity string) error {
+	token, err := s.security.GetRandomToken()
+	if err != nil {
+		return err
+	}
+
+	ttl := 7 * 24 * time.Hour
+	if err := s.otpStore.StoreSessionsRevocation(token, user.ID, ttl); err != nil {
+		return err
+	}
+
+	device := useragent.Parse(userAgent).String()
+	location := ip
+	if geoCity != "" {
+		location = geoCity + ", " + ip
+	}
+
+	link := s.baseBackendURL + "/v1/sessions/revoke?token=" + token
+
+	expiresAt := time.Now().In(time.UTC).Add(ttl)
+	m := &OutboundMessage{
+		Kind:      OutboundMessageKindSMS,
+		UserID:    &user.ID,
+		Recipient: user.Phone,
+		Payload: map[string]string{
+			"text": "Вход в Woman Club: " + device + ", " + location + ". Если это были не Вы, завершите все сеансы: " + link,
+		},
+		ExpiresAt: &expiresAt,
+	}
+
+	if user.Email != nil && user.Status.IsEmailConfirmed() {
+		var name string
+		if user.FirstName != nil {
+			name = *user.FirstName
+		}
+
+		m.Kind = OutboundMessageKindEmailNewDevice
+		m.Recipient = *user.Email
+		m.Payload = map[string]string{
+			"name":     name,
+			"device":   device,
+			"location": location,
+			"link":     link,
+		}
+	}
+
+	_, err = s.db.EnqueueOutboundMessage(m)
+
+	return err
+}
+
+// RevokeSessionsByLink logs the user out everywhere by the link of a new device notification.
+func (s *service) RevokeSessionsByLink(token string) error {
+	userID, err := s.otpStore.LoadSessionsRevocation(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.RevokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	if err := s.revokeAccessTokens(userID); err != nil {
+		return err
+	}
+
+	return s.db.CreateAuditEvent(&AuditEvent{
+		Type:    AuditEventTypeSessionsRevoked,
+		UserID:  &userID,
+		Details: map[string]string{"initiator": "user"},
+	})
+}
+
+var otpTexts = map[OTPType]string{
+	OTPTypeRegistration: "Ваш код для регистрации в Woman Club: ",
+	OTPTypeLogin:        "Ваш код для входа в Woman Club: ",
+	OTPTypeStepUp:       "Ваш код для подтверждения действия в Woman Club: ",
+	OTPTypePhoneOld:     "Ваш код для смены номера телефона в Woman Club: ",
+	OTPTypePhoneNew:     "Ваш код для подтверждения нового номера в Woman Club: ",
+}
+
+// sendOTP stores a code and puts it to the outbox of the chosen channel.
+// A code is useless after it expires, so are the messages.
+func (s *service) sendOTP(otpType OTPType, requestID uuid.UUID, user *User, channel OTPChannel, code string) error {
+	expiresAt := time.Now().In(time.UTC).Add(5 * time.Minute)
+	m := &OutboundMessage{
+		UserID:    &user.ID,
+		Recipient: user.Phone,
+		RequestID: &requestID,
+		ExpiresAt: &expiresAt,
+	}
+
+	switch channel {
+	case OTPChannelSMS, "":
+		channel = OTPChannelSMS
+		m.Kind = OutboundMessageKindSMS
+		m.Payload = map[string]string{
+			"text": otpTexts[otpType] + code,
+		}
+	case OTPChannelVoice:
+		// Digits are separated to be read out one by one
+		spelled := strings.Join(strings.Split(code, ""), " ")
+
+		m.Kind = OutboundMessageKindVoice
+		m.Payload = map[string]string{
+			"text": otpTexts[otpType] + spelled + ". Повторяю: " + spelled + ".",
+		}
+	case OTPChannelMessenger:
+		m.Kind = OutboundMessageKindMessenger
+		m.Payload = map[string]string{
+			"text": otpTexts[otpType] + code,
+		}
+	case OTPChannelEmail:
+		// Only a confirmed email proves that it belongs to the user
+		if user.Email == nil || !user.Status.IsEmailConfirmed() {
+			return ErrOTPChannelUnavailable
+		}
+
+		var name string
+		if user.FirstName != nil {
+			name = *user.FirstName
+		}
+
+		m.Kind = OutboundMessageKindEmailOTP
+		m.Recipient = *user.Email
+		m.Payload = map[string]string{
+			"name": name,
+			"code": code,
+		}
+	default:
+		return ErrOTPChannelUnavailable
+	}
+
+	if err := s.otpStore.Store(otpType, requestID, user.Phone, code, channel); err != nil {
+		return err
+	}
+
+	_, err := s.db.EnqueueOutboundMessage(m)
+
+	return err
+}
+
+// enqueueEmailConfirmation puts a confirmation email to the outbox. It is useless after the token expires.
+func (s *service) enqueueEmailConfirmation(userID int, emailAddress, name, token string) error {
+	expiresAt := time.Now().In(time.UTC).Add(24 * time.Hour)
+
+	_, err := s.db.EnqueueOutboundMessage(&OutboundMessage{
+		Kind:      OutboundMessageKindEmailConfirmation,
+		UserID:    &userID,
+		Recipient: emailAddress,
+		Payload: map[string]string{
+			"name":  name,
+			"token": token,
+		},
+		ExpiresAt: &expiresAt,
+	})
+
+	return err
+}
+
+// Authorize checks that roles of the current user grant the permission.
+func (s *service) Authorize(ctx context.Context, permission Permission) error {
+	roles, _ := ctx.Value(ContextRoles).([]string)
+	if len(roles) == 0 {
+		return ErrForbidden
+	}
+
+	permissions, err := s.db.GetRolePermissions(roles)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range permissions {
+		if p == permission {
+			return nil
+		}
+	}
+
+	return ErrForbidden
+}
+
+func (s *service) AdminSearchUsers(r *UserSearchRequest) ([]*User, error) {
+	users, err := s.db.SearchUsers(r.Query, r.Limit, r.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		s.setAvatarURLs(user)
+	}
+
+	return users, nil
+}
+
+func (s *service) AdminGetUser(userID int) (*User, error) {
+	user, err := s.db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setAvatarURLs(user)
+
+	return user, nil
+}
+
+func (s *service) AdminRevokeSessions(ctx context.Context, userID int) error {
+	if _, err := s.db.GetUser(userID); err != nil {
+		return err
+	}
+
+	if err := s.db.RevokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	if err := s.revokeAccessTokens(userID); err != nil {
+		return err
+	}
+
+	return s.auditAdminAction(ctx, AuditEventTypeSessionsRevoked, userID, nil)
+}
+
+// AdminSetUserState restricts the account or lifts a restriction, a restricted user is logged out everywhere.
+func (s *service) AdminSetUserState(ctx context.Context, userID int, r *UserStateRequest) error {
+	if err := s.db.SetUserState(userID, r); err != nil {
+		return err
+	}
+
+	if r.State != AccountStateActive {
+		if err := s.db.RevokeAllSessions(userID); err != nil {
+			return err
+		}
+
+		if err := s.revokeAccessTokens(userID); err != nil {
+			return err
+		}
+	}
+
+	details := map[string]string{"state": string(r.State)}
+	if r.Reason != "" {
+		details["reason"] = r.Reason
+	}
+	if r.ExpiresAt != nil {
+		details["expires_at"] = r.ExpiresAt.Format(time.RFC3339)
+	}
+
+	return s.auditAdminAction(ctx, AuditEventTypeUserStateChanged, userID, details)
+}
+
+func (s *service) AdminGetOTPDeliveryStatus(requestID uuid.UUID) (*OTPDeliveryStatus, error) {
+	return s.db.GetOTPDeliveryStatus(requestID, nil)
+}
+
+// auditAdminAction records an action of the current staff member on the user.
+func (s *service) auditAdminAction(ctx context.Context, t AuditEventType, userID int, details map[string]string) error {
+	actorID, ok := ctx.Value(ContextUserID).(int)
+	if !ok {
+		return ErrInvalidInputData
+	}
+
+	if details == nil {
+		details = map[string]string{}
+	}
+	details["actor_id"] = strconv.Itoa(actorID)
+
+	return s.db.CreateAuditEvent(&AuditEvent{
+		Type:    t,
+		UserID:  &userID,
+		Details: details,
+	})
 }
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"github.com/google/uuid"
+	"strconv"
 	"time"
 )
 
@@ -10,6 +11,9 @@ type ContextKey string
 const (
 	ContextUserID       ContextKey = "ctx_user_id"
 	ContextRefreshToken ContextKey = "ctx_refresh_token"
+	ContextRoles        ContextKey = "ctx_roles"
+	ContextSessionID    ContextKey = "ctx_session_id"
+	ContextAuthTime     ContextKey = "ctx_auth_time"
 )
 
 type RegistrationRequestType string
@@ -28,7 +32,12 @@ type RegistrationRequest struct {
 }
 
 type RegistrationRequestStartPayload struct {
-	Phone string
+	Phone   string
+	Channel OTPChannel
+}
+
+type RegistrationRequestResendPayload struct {
+	Channel OTPChannel
 }
 
 type RegistrationRequestConfirmPayload struct {
@@ -43,8 +52,10 @@ type RegistrationRequestFinishPayload struct {
 	City       string
 
 	Fingerprint string
+	ClientType  ClientType
 	UserAgent   string
 	IP          string
+	GeoCity     string
 }
 
 type LoginRequestType string
@@ -53,6 +64,7 @@ const (
 	LoginRequestTypeStart   LoginRequestType = "start"
 	LoginRequestTypeResend  LoginRequestType = "resend"
 	LoginRequestTypeConfirm LoginRequestType = "confirm"
+	LoginRequestTypeTOTP    LoginRequestType = "totp"
 )
 
 type LoginRequest struct {
@@ -62,22 +74,116 @@ type LoginRequest struct {
 }
 
 type LoginRequestStartPayload struct {
-	Phone string
+	Phone   string
+	Channel OTPChannel
+}
+
+type LoginRequestResendPayload struct {
+	Channel OTPChannel
 }
 
 type LoginRequestConfirmPayload struct {
 	SMSCode string
 
 	Fingerprint string
+	ClientType  ClientType
 	UserAgent   string
 	IP          string
+	GeoCity     string
+}
+
+type LoginRequestTOTPPayload struct {
+	TOTPCode     string
+	RecoveryCode string
+
+	Fingerprint string
+	ClientType  ClientType
+	UserAgent   string
+	IP          string
+	GeoCity     string
+}
+
+type PhoneChangeRequestType string
+
+const (
+	PhoneChangeRequestTypeStart      PhoneChangeRequestType = "start"
+	PhoneChangeRequestTypeResend     PhoneChangeRequestType = "resend"
+	PhoneChangeRequestTypeConfirmOld PhoneChangeRequestType = "confirm-old"
+	PhoneChangeRequestTypeConfirmNew PhoneChangeRequestType = "confirm-new"
+)
+
+type PhoneChangeRequest struct {
+	Type      PhoneChangeRequestType
+	RequestID uuid.UUID
+	Payload   interface{}
+}
+
+// Channel is the one of the old number, the email channel is a fallback for a lost SIM.
+type PhoneChangeRequestStartPayload struct {
+	Phone   string
+	Channel OTPChannel
+}
+
+type PhoneChangeRequestResendPayload struct {
+	Channel OTPChannel
+}
+
+type PhoneChangeRequestConfirmPayload struct {
+	Code string
+}
+
+// PhoneChange is a phone change in progress, the new number is verified after the old one.
+type PhoneChange struct {
+	UserID     int
+	OldPhone   string
+	NewPhone   string
+	OldChannel OTPChannel
+	// OldConfirmed is set once the code sent by OldChannel is verified
+	OldConfirmed bool
+}
+
+type StepUpRequestType string
+
+const (
+	StepUpRequestTypeStart   StepUpRequestType = "start"
+	StepUpRequestTypeConfirm StepUpRequestType = "confirm"
+	StepUpRequestTypeTOTP    StepUpRequestType = "totp"
+)
+
+// StepUpRequest re-authenticates the user of the current session with a fresh code.
+type StepUpRequest struct {
+	Type      StepUpRequestType
+	RequestID uuid.UUID
+	Channel   OTPChannel
+	Code      string
 }
 
 type AuthResponse struct {
-	Status       string
-	RequestID    uuid.UUID
-	AccessToken  string
-	RefreshToken uuid.UUID
+	Status           string
+	RequestID        uuid.UUID
+	AccessToken      string
+	RefreshToken     uuid.UUID
+	RefreshExpiresAt time.Time
+}
+
+// ClientType selects a session policy, service clients are the registered ones.
+type ClientType string
+
+const (
+	ClientTypeWeb     ClientType = "web"
+	ClientTypeMobile  ClientType = "mobile"
+	ClientTypeService ClientType = "service"
+)
+
+var ClientTypes = []ClientType{ClientTypeWeb, ClientTypeMobile, ClientTypeService}
+
+type SessionPolicy struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// Sliding sessions are prolonged by every refresh, absolute ones end RefreshTokenTTL after the login
+	Sliding bool
+	// Older sessions of the client type are revoked
+	MaxSessions int
 }
 
 type OTPType string
@@ -85,8 +191,91 @@ type OTPType string
 const (
 	OTPTypeRegistration OTPType = "registration"
 	OTPTypeLogin        OTPType = "login"
+	OTPTypeStepUp       OTPType = "step_up"
+	OTPTypePhoneOld     OTPType = "phone_change_old"
+	OTPTypePhoneNew     OTPType = "phone_change_new"
+)
+
+type OTPChannel string
+
+const (
+	OTPChannelSMS       OTPChannel = "sms"
+	OTPChannelVoice     OTPChannel = "voice"
+	OTPChannelEmail     OTPChannel = "email"
+	OTPChannelMessenger OTPChannel = "messenger"
+)
+
+type OutboundMessageKind string
+
+const (
+	OutboundMessageKindSMS               OutboundMessageKind = "sms"
+	OutboundMessageKindEmailConfirmation OutboundMessageKind = "email_confirmation"
+	OutboundMessageKindEmailOTP          OutboundMessageKind = "email_otp"
+	OutboundMessageKindVoice             OutboundMessageKind = "voice"
+	OutboundMessageKindMessenger         OutboundMessageKind = "messenger"
+	OutboundMessageKindEmailNewDevice    OutboundMessageKind = "email_new_device"
 )
 
+type OutboundMessageStatus string
+
+const (
+	OutboundMessageStatusPending    OutboundMessageStatus = "pending"
+	OutboundMessageStatusProcessing OutboundMessageStatus = "processing"
+	OutboundMessageStatusSent       OutboundMessageStatus = "sent"
+	OutboundMessageStatusDead       OutboundMessageStatus = "dead"
+)
+
+type OutboundMessage struct {
+	ID                int
+	Kind              OutboundMessageKind
+	UserID            *int
+	Recipient         string
+	RequestID         *uuid.UUID
+	Payload           map[string]string
+	Status            OutboundMessageStatus
+	Attempts          int
+	NextAttemptAt     time.Time
+	ExpiresAt         *time.Time
+	LastError         *string
+	Provider          *string
+	ProviderMessageID *string
+	SentAt            *time.Time
+	CreatedAt         time.Time
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusUnknown     DeliveryStatus = "unknown"
+	DeliveryStatusEnroute     DeliveryStatus = "enroute"
+	DeliveryStatusDelivered   DeliveryStatus = "delivered"
+	DeliveryStatusUndelivered DeliveryStatus = "undelivered"
+	DeliveryStatusExpired     DeliveryStatus = "expired"
+	DeliveryStatusRejected    DeliveryStatus = "rejected"
+)
+
+type DeliveryReceipt struct {
+	Provider          string
+	ProviderMessageID string
+	Status            DeliveryStatus
+	ErrorCode         string
+	Payload           []byte
+}
+
+type OTPDeliveryStatus struct {
+	MessageStatus  OutboundMessageStatus
+	DeliveryStatus DeliveryStatus
+	Provider       *string
+	ErrorCode      *string
+	SentAt         *time.Time
+	UpdatedAt      time.Time
+}
+
+type MessageReceipt struct {
+	Provider  string
+	MessageID string
+}
+
 type User struct {
 	ID         int
 	Status     UserStatus
@@ -97,8 +286,105 @@ type User struct {
 	Birthday   *time.Time
 	City       *string
 	Email      *string
-	CreatedAt  time.Time
-	UpdatedAt  *time.Time
+	Roles      []string
+
+	// Avatar is the key prefix of the avatar variants, URLs are set by the service
+	Avatar     *string
+	AvatarURLs map[string]string
+
+	// Reason and expiry of a restrictive state
+	StatusReason    *string
+	StatusExpiresAt *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+// State of the account, suspensions and bans are lifted once they expire.
+func (u *User) State() AccountState {
+	expired := u.StatusExpiresAt != nil && time.Now().After(*u.StatusExpiresAt)
+
+	switch {
+	case u.Status.IsPendingDeletion():
+		return AccountStatePendingDeletion
+	case u.Status.IsBanned() && !expired:
+		return AccountStateBanned
+	case u.Status.IsSuspended() && !expired:
+		return AccountStateSuspended
+	default:
+		return AccountStateActive
+	}
+}
+
+// Version changes with every update of the user row, in microseconds of the last update.
+func (u *User) Version() int64 {
+	t := u.CreatedAt
+	if u.UpdatedAt != nil {
+		t = *u.UpdatedAt
+	}
+
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+// AvatarKeys are blob keys of the avatar variants by their names, nil without an avatar.
+func (u *User) AvatarKeys() map[string]string {
+	if u.Avatar == nil {
+		return nil
+	}
+
+	keys := make(map[string]string, len(AvatarSizes))
+	for name := range AvatarSizes {
+		keys[name] = *u.Avatar + "/" + name + ".jpg"
+	}
+
+	return keys
+}
+
+// DeletionRequested is true while the user may cancel the deletion of the account by logging in.
+func (u *User) DeletionRequested() bool {
+	return u.Status.IsPendingDeletion() &&
+		u.StatusReason != nil && *u.StatusReason == DeletionReasonUserRequest &&
+		u.StatusExpiresAt != nil && time.Now().Before(*u.StatusExpiresAt)
+}
+
+// CheckState returns an error for a restricted account.
+func (u *User) CheckState() error {
+	switch u.State() {
+	case AccountStatePendingDeletion:
+		return ErrUserPendingDeletion
+	case AccountStateBanned:
+		return ErrUserBanned
+	case AccountStateSuspended:
+		return ErrUserSuspended
+	default:
+		return nil
+	}
+}
+
+type AccountState string
+
+const (
+	AccountStateActive          AccountState = "active"
+	AccountStateSuspended       AccountState = "suspended"
+	AccountStateBanned          AccountState = "banned"
+	AccountStatePendingDeletion AccountState = "pending_deletion"
+)
+
+// AvatarSizes are sides of the square variants of an avatar by their names.
+var AvatarSizes = map[string]int{
+	"small":  64,
+	"medium": 256,
+	"large":  512,
+}
+
+// DeletionReasonUserRequest is the status reason of a deletion requested by the user themselves.
+const DeletionReasonUserRequest = "user_request"
+
+// UserStateRequest changes the state of an account, ExpiresAt is empty for an indefinite restriction.
+type UserStateRequest struct {
+	State     AccountState
+	Reason    string
+	ExpiresAt *time.Time
 }
 
 type UserStatus int
@@ -123,29 +409,360 @@ func (s UserStatus) IsEmailConfirmed() bool {
 	return s&0b00010000 == 0b00010000
 }
 
+func (s UserStatus) IsSuspended() bool {
+	return s&0b00100000 == 0b00100000
+}
+
+func (s UserStatus) IsBanned() bool {
+	return s&0b01000000 == 0b01000000
+}
+
+func (s UserStatus) IsPendingDeletion() bool {
+	return s&0b10000000 == 0b10000000
+}
+
+// Permission is granted to users through their roles.
+type Permission string
+
+const (
+	PermissionUsersRead      Permission = "users:read"
+	PermissionSessionsRevoke Permission = "sessions:revoke"
+	PermissionUsersRestrict  Permission = "users:restrict"
+)
+
+type UserSearchRequest struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
 type RefreshSession struct {
 	ID           int
 	UserID       int
+	ClientType   ClientType
 	RefreshToken string
 	Fingerprint  string
 	UserAgent    string
 	IP           string
 	ExpiresAt    time.Time
 	CreatedAt    time.Time
-}
 
-type ProfileUpdateRequest struct {
-	FirstName  string
-	MiddleName string
-	LastName   string
-	Birthday   string
-	City       string
-	Email      string
+	// Rotation creates a child session in the same family
+	FamilyID  uuid.UUID
+	ParentID  *int
+	RotatedAt *time.Time
+
+	// Time of the last interactive authentication, kept by the rotation
+	AuthTime time.Time
+
+	// Current is set for the session of the request
+	Current bool
 }
 
-type JWTRequest struct {
+// KnownDevice is a device a user has logged in from, told by the fingerprint.
+// IP and city are the last seen ones, city is empty unless the proxy resolves it.
+type KnownDevice struct {
+	ID          int
 	UserID      int
+	Fingerprint string
 	UserAgent   string
 	IP          string
+	City        string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+type TOTP struct {
+	UserID      int
+	Secret      string
+	ConfirmedAt *time.Time
+	CreatedAt   time.Time
+}
+
+func (t *TOTP) IsEnabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type WebAuthnCredential struct {
+	ID           int
+	UserID       int
+	CredentialID []byte
+	PublicKey    []byte
+	SignCount    uint32
+	AAGUID       []byte
+	LastUsedAt   *time.Time
+	CreatedAt    time.Time
+}
+
+// WebAuthnSession is a pending ceremony, UserID is empty for a login with a discoverable credential.
+type WebAuthnSession struct {
+	Challenge []byte
+	UserID    int
+}
+
+type WebAuthnRegistrationOptions struct {
+	Challenge            []byte
+	RPID                 string
+	RPName               string
+	UserHandle           []byte
+	UserName             string
+	UserDisplayName      string
+	ExcludeCredentialIDs [][]byte
+	UserVerification     bool
+	Timeout              time.Duration
+}
+
+type WebAuthnLoginOptions struct {
+	Challenge          []byte
+	RPID               string
+	AllowCredentialIDs [][]byte
+	UserVerification   bool
+	Timeout            time.Duration
+}
+
+type WebAuthnAttestation struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+type WebAuthnAssertion struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+type WebAuthnLoginRequest struct {
+	RequestID   uuid.UUID
+	Assertion   *WebAuthnAssertion
 	Fingerprint string
+	ClientType  ClientType
+	UserAgent   string
+	IP          string
+	GeoCity     string
+}
+
+// SigningKey is a JWT signing key, PrivateKey is PKCS #8 DER encrypted by the key-encryption key.
+type SigningKey struct {
+	KID         string
+	Algorithm   string
+	PrivateKey  []byte
+	ActivatesAt time.Time
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// JSONWebKey is a public key (RFC 7517), fields are base64url encoded.
+type JSONWebKey struct {
+	KeyType   string
+	KeyID     string
+	Use       string
+	Algorithm string
+	N         string
+	E         string
+	Curve     string
+	X         string
+	Y         string
+}
+
+// AccessTokenClaims of a token issued to an OAuth client have ClientID and Scopes.
+type AccessTokenClaims struct {
+	ID        string
+	UserID    int
+	SessionID int
+	FamilyID  uuid.UUID
+	ClientID  string
+	Scopes    []string
+	Roles     []string
+	AuthTime  time.Time
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+const (
+	OIDCScopeOpenID  = "openid"
+	OIDCScopeProfile = "profile"
+	OIDCScopePhone   = "phone"
+	OIDCScopeEmail   = "email"
+
+	// Scopes of service clients allowed to get tokens of users
+	ScopeAllUsers = "user:*"
+)
+
+// UserScope is a scope of a service client allowed to get tokens of the user.
+func UserScope(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthClient is a relying party of the OpenID Connect provider, public clients have no secret.
+type OAuthClient struct {
+	ClientID             string
+	Name                 string
+	SecretHash           *string
+	TLSClientAuthSubject *string
+	RedirectURIs         []string
+	Scopes               []string
+	GrantTypes           []string
+	CreatedAt            time.Time
+}
+
+func (c *OAuthClient) IsConfidential() bool {
+	return c.SecretHash != nil
+}
+
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *OAuthClient) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *OAuthClient) HasGrantType(grantType string) bool {
+	for _, g := range c.GrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type AuditEventType string
+
+const (
+	AuditEventTypeJWTIssued        AuditEventType = "jwt_issued"
+	AuditEventTypeSessionsRevoked  AuditEventType = "sessions_revoked"
+	AuditEventTypeRefreshReused    AuditEventType = "refresh_token_reused"
+	AuditEventTypeUserStateChanged AuditEventType = "user_state_changed"
+	AuditEventTypeNewDeviceLogin   AuditEventType = "new_device_login"
+	AuditEventTypePhoneChanged     AuditEventType = "phone_changed"
+
+	AuditEventTypeDeletionRequested AuditEventType = "account_deletion_requested"
+	AuditEventTypeDeletionCancelled AuditEventType = "account_deletion_cancelled"
+	AuditEventTypeAccountPurged     AuditEventType = "account_purged"
+)
+
+type AuditEvent struct {
+	ID        int
+	Type      AuditEventType
+	UserID    *int
+	ClientID  *string
+	IP        string
+	UserAgent string
+	Details   map[string]string
+	CreatedAt time.Time
+}
+
+// DataExport is everything kept about the user, refresh tokens and message payloads excluded.
+type DataExport struct {
+	User         *User
+	Sessions     []*RefreshSession
+	KnownDevices []*KnownDevice
+	AuditEvents  []*AuditEvent
+	Messages     []*OutboundMessage
+}
+
+type OIDCAuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+type AuthorizationCode struct {
+	ClientID      string
+	UserID        int
+	RedirectURI   string
+	Scopes        []string
+	Nonce         string
+	CodeChallenge string
+}
+
+type OIDCTokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+type OIDCTokenResponse struct {
+	AccessToken string
+	IDToken     string
+	ExpiresIn   time.Duration
+	Scopes      []string
+}
+
+type IDTokenClaims struct {
+	UserID   int
+	ClientID string
+	Nonce    string
+}
+
+// ProfileUpdateRequest is a merge patch of the profile (RFC 7396): absent fields are left as they are,
+// nil values clear the fields.
+type ProfileUpdateRequest struct {
+	Fields map[ProfileField]*string
+	// IfMatch is the version of the profile the client has seen, nil skips the check
+	IfMatch *int64
+}
+
+// ProfileField is a user column that may be patched.
+type ProfileField string
+
+const (
+	ProfileFieldFirstName  ProfileField = "first_name"
+	ProfileFieldMiddleName ProfileField = "middle_name"
+	ProfileFieldLastName   ProfileField = "last_name"
+	ProfileFieldBirthday   ProfileField = "birthday"
+	ProfileFieldCity       ProfileField = "city"
+)
+
+var ProfileFields = []ProfileField{
+	ProfileFieldFirstName,
+	ProfileFieldMiddleName,
+	ProfileFieldLastName,
+	ProfileFieldBirthday,
+	ProfileFieldCity,
+}
+
+// JWTRequest is made by a service client authenticated by the secret or the TLS certificate subject.
+type JWTRequest struct {
+	ClientID     string
+	ClientSecret string
+	TLSSubject   string
+	UserID       int
+	UserAgent    string
+	IP           string
+	Fingerprint  string
 }
@@ -0,0 +1,20 @@
+package blob
+
+import (
+	"fmt"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Creating a new blob store with the configured driver.
+func NewAdapter(logger *logrus.Logger, config *Config) (domain.BlobStore, error) {
+	switch config.Driver {
+	case "local":
+		return newLocal(logger, config.Local)
+	case "s3":
+		return newS3(logger, config.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob driver %q", config.Driver)
+	}
+}
@@ -0,0 +1,27 @@
+package blob
+
+import "time"
+
+type Config struct {
+	Driver string `long:"driver" env:"DRIVER" description:"Blob store driver (local, s3)" default:"local"`
+
+	Local *LocalConfig `group:"Blob local driver args" namespace:"local" env-namespace:"LOCAL"`
+	S3    *S3Config    `group:"Blob S3 driver args" namespace:"s3" env-namespace:"S3"`
+}
+
+type LocalConfig struct {
+	Dir     string `long:"dir" env:"DIR" description:"Directory to keep files in" default:"data/blobs"`
+	BaseURL string `long:"base-url" env:"BASE_URL" description:"URL the directory is served at" default:"http://localhost:8080/blobs"`
+}
+
+type S3Config struct {
+	Endpoint        string        `long:"endpoint" env:"ENDPOINT" description:"S3-compatible API endpoint" default:"https://s3.amazonaws.com"`
+	Region          string        `long:"region" env:"REGION" description:"Region requests are signed for" default:"us-east-1"`
+	Bucket          string        `long:"bucket" env:"BUCKET" description:"Bucket name"`
+	AccessKeyID     string        `long:"access-key-id" env:"ACCESS_KEY_ID" description:"Access key ID"`
+	SecretAccessKey string        `long:"secret-access-key" env:"SECRET_ACCESS_KEY" description:"Secret access key"`
+	PathStyle       bool          `long:"path-style" env:"PATH_STYLE" description:"Address the bucket in the path, as local stand-ins like MinIO expect"`
+	ACL             string        `long:"acl" env:"ACL" description:"Canned ACL of uploaded objects, e.g. public-read"`
+	PublicURL       string        `long:"public-url" env:"PUBLIC_URL" description:"URL objects are served at, e.g. by a CDN, the bucket URL if empty"`
+	Timeout         time.Duration `long:"timeout" env:"TIMEOUT" description:"Request timeout" default:"10s"`
+}
@@ -0,0 +1,84 @@
+package blob
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// local keeps files in a directory served by the HTTP adapter or a reverse proxy.
+type local struct {
+	logger *logrus.Logger
+	config *LocalConfig
+}
+
+func newLocal(logger *logrus.Logger, config *LocalConfig) (*local, error) {
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("blob local driver: %w", err)
+	}
+
+	return &local{
+		logger: logger,
+		config: config,
+	}, nil
+}
+
+func (l *local) Put(key, _ string, data []byte) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		l.logger.WithError(err).Error("Error while creating a blob directory!")
+		return domain.ErrInternalBlobStore
+	}
+
+	// A reader never sees a partly written file
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		l.logger.WithError(err).Error("Error while writing a blob!")
+		return domain.ErrInternalBlobStore
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		l.logger.WithError(err).Error("Error while writing a blob!")
+		return domain.ErrInternalBlobStore
+	}
+
+	return nil
+}
+
+func (l *local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		l.logger.WithError(err).Error("Error while deleting a blob!")
+		return domain.ErrInternalBlobStore
+	}
+
+	return nil
+}
+
+func (l *local) URL(key string) string {
+	return strings.TrimSuffix(l.config.BaseURL, "/") + "/" + key
+}
+
+// path keeps keys within the directory.
+func (l *local) path(key string) (string, error) {
+	path := filepath.Join(l.config.Dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(l.config.Dir)+string(filepath.Separator)) {
+		l.logger.WithField("key", key).Error("Blob key is outside of the directory!")
+		return "", domain.ErrInternalBlobStore
+	}
+
+	return path, nil
+}
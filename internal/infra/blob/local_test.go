@@ -0,0 +1,93 @@
+package blob
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"trainee-assignment-backend/internal/domain"
+)
+
+func TestLocalPutDelete(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := newLocal(newTestLogger(), &LocalConfig{Dir: dir, BaseURL: "http://localhost:8080/blobs/"})
+	if err != nil {
+		t.Fatalf("newLocal() error = %v", err)
+	}
+
+	if err := l.Put("avatars/1/a.png", "image/png", []byte("image")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	path := filepath.Join(dir, "avatars", "1", "a.png")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "image" {
+		t.Errorf("file = %q, want image", data)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file is left, stat error = %v", err)
+	}
+
+	if got := l.URL("avatars/1/a.png"); got != "http://localhost:8080/blobs/avatars/1/a.png" {
+		t.Errorf("URL() = %q", got)
+	}
+
+	if err := l.Delete("avatars/1/a.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file is not deleted, stat error = %v", err)
+	}
+
+	// Deleting a missing file isn't an error
+	if err := l.Delete("avatars/1/a.png"); err != nil {
+		t.Errorf("Delete() of a missing file error = %v", err)
+	}
+}
+
+func TestLocalPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "blobs")
+
+	l, err := newLocal(newTestLogger(), &LocalConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("newLocal() error = %v", err)
+	}
+
+	outside := filepath.Join(root, "outside")
+	if err := ioutil.WriteFile(outside, []byte("keep"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	keys := []string{
+		"",
+		".",
+		"..",
+		"../outside",
+		"avatars/../../outside",
+		"../blobs-sibling/a.png",
+	}
+
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			if err := l.Put(key, "image/png", []byte("image")); err != domain.ErrInternalBlobStore {
+				t.Errorf("Put() error = %v, want %v", err, domain.ErrInternalBlobStore)
+			}
+
+			if err := l.Delete(key); err != domain.ErrInternalBlobStore {
+				t.Errorf("Delete() error = %v, want %v", err, domain.ErrInternalBlobStore)
+			}
+		})
+	}
+
+	if data, err := ioutil.ReadFile(outside); err != nil || string(data) != "keep" {
+		t.Errorf("file outside of the directory is changed: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "blobs-sibling")); !os.IsNotExist(err) {
+		t.Errorf("sibling directory is created, stat error = %v", err)
+	}
+}
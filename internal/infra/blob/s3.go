@@ -0,0 +1,204 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// s3 keeps objects in a bucket of an S3-compatible storage, requests are signed with AWS Signature Version 4.
+type s3 struct {
+	logger   *logrus.Logger
+	config   *S3Config
+	client   *http.Client
+	endpoint *url.URL
+}
+
+func newS3(logger *logrus.Logger, config *S3Config) (*s3, error) {
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("blob s3 driver: bucket and credentials are required")
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("blob s3 driver: invalid endpoint %q", config.Endpoint)
+	}
+
+	return &s3{
+		logger: logger,
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+		},
+		endpoint: endpoint,
+	}, nil
+}
+
+func (s *s3) Put(key, contentType string, data []byte) error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	// Keys of new content are new, so objects never change
+	header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	if s.config.ACL != "" {
+		header.Set("X-Amz-Acl", s.config.ACL)
+	}
+
+	return s.do(http.MethodPut, key, header, data)
+}
+
+func (s *s3) Delete(key string) error {
+	return s.do(http.MethodDelete, key, http.Header{}, nil)
+}
+
+func (s *s3) URL(key string) string {
+	if s.config.PublicURL != "" {
+		return strings.TrimSuffix(s.config.PublicURL, "/") + "/" + key
+	}
+
+	return s.objectURL(key).String()
+}
+
+// objectURL addresses the bucket by the host or, for path-style requests, by the first path segment.
+func (s *s3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.config.PathStyle {
+		path += "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+	u.Path = path + "/" + key
+	u.RawPath = escapePath(u.Path)
+
+	return &u
+}
+
+func (s *s3) do(method, key string, header http.Header, body []byte) error {
+	u := s.objectURL(key)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		s.logger.WithError(err).Error("Error while creating an S3 request!")
+		return domain.ErrInternalBlobStore
+	}
+	req.Header = header
+	s.sign(req, body, time.Now().In(time.UTC))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.logger.WithError(err).Error("Error while calling the S3 API!")
+		return domain.ErrInternalBlobStore
+	}
+	defer resp.Body.Close()
+
+	// Deleting a missing object isn't an error for S3 either
+	if resp.StatusCode/100 != 2 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		s.logger.WithFields(logrus.Fields{
+			"status": resp.StatusCode,
+			"body":   string(b),
+			"key":    key,
+		}).Error("S3 API responded with an error!")
+		return domain.ErrInternalBlobStore
+	}
+
+	return nil
+}
+
+// sign adds the Authorization header of AWS Signature Version 4, every header of the request is signed.
+func (s *s3) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID,
+		scope,
+		signedHeaders,
+		signature,
+	))
+}
+
+// escapePath encodes every byte but the unreserved ones and slashes, as the signature expects.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -65,6 +65,83 @@ func (a *adapter) SendEmailConfirmation(address, name, token string) error {
 	m.SetHeader("Subject", "Подтвердите ваш email!")
 	m.SetBody("text/html", emailBody)
 
+	return a.send(m)
+}
+
+func (a *adapter) SendOTP(address, name, code string) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: name,
+			Intros: []string{
+				"Ваш одноразовый код для Woman Club:",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "Код", Value: code},
+			},
+			Outros: []string{
+				"Код действует 5 минут. Если Вы не запрашивали код, просто проигнорируйте это письмо.",
+			},
+		},
+	}
+
+	emailBody, err := a.hermes.GenerateHTML(email)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while generating an HTML!")
+		return domain.ErrInternalEmail
+	}
+
+	m := gomail.NewMessage()
+	m.SetAddressHeader("From", a.config.Username, "Trainee Assignment")
+	m.SetHeader("To", address)
+	m.SetHeader("Subject", "Ваш одноразовый код")
+	m.SetBody("text/html", emailBody)
+
+	return a.send(m)
+}
+
+func (a *adapter) SendNewDeviceLogin(address, name, device, location, link string) error {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Name: name,
+			Intros: []string{
+				"В Ваш аккаунт Woman Club выполнен вход с нового устройства или из нового места.",
+			},
+			Dictionary: []hermes.Entry{
+				{Key: "Устройство", Value: device},
+				{Key: "Место", Value: location},
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Если это были не Вы, завершите все сеансы и войдите заново:",
+					Button: hermes.Button{
+						Color: "#000000",
+						Text:  "Это был не я",
+						Link:  link,
+					},
+				},
+			},
+			Outros: []string{
+				"Если это были Вы, просто проигнорируйте это письмо.",
+			},
+		},
+	}
+
+	emailBody, err := a.hermes.GenerateHTML(email)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while generating an HTML!")
+		return domain.ErrInternalEmail
+	}
+
+	m := gomail.NewMessage()
+	m.SetAddressHeader("From", a.config.Username, "Trainee Assignment")
+	m.SetHeader("To", address)
+	m.SetHeader("Subject", "Вход с нового устройства")
+	m.SetBody("text/html", emailBody)
+
+	return a.send(m)
+}
+
+func (a *adapter) send(m *gomail.Message) error {
 	dialer := gomail.NewDialer(a.config.Host, a.config.Port, a.config.Username, a.config.Password)
 	dialer.TLSConfig = &tls.Config{
 		InsecureSkipVerify: true,
@@ -2,13 +2,12 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
-	"github.com/dgrijalva/jwt-go"
-	"github.com/go-chi/jwtauth"
 	"github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"trainee-assignment-backend/internal/domain"
 )
 
@@ -18,9 +17,6 @@ type adapter struct {
 	service domain.Service
 
 	server *http.Server
-
-	// jwt
-	jwtAuth *jwtauth.JWTAuth
 }
 
 // Creating a new HTTP adapter.
@@ -31,25 +27,6 @@ func NewAdapter(logger *logrus.Logger, config *Config, service domain.Service) (
 		service: service,
 	}
 
-	// Read JWT signing key
-	fileWithAccessToken, err := os.Open(a.config.JWTPrivateKey)
-	if err != nil {
-		a.logger.WithError(err).Error("Error while opening file!")
-		return nil, err
-	}
-
-	//noinspection ALL
-	defer fileWithAccessToken.Close()
-
-	bytes, err := ioutil.ReadAll(fileWithAccessToken)
-	if err != nil {
-		a.logger.WithError(err).Error("Error while reading file!")
-		return nil, err
-	}
-
-	jwtAuth := jwtauth.New(jwt.SigningMethodHS256.Name, bytes, nil)
-	a.jwtAuth = jwtAuth
-
 	r, err := a.newRouter()
 	if err != nil {
 		logger.WithError(err).Error("Error while creating new router!")
@@ -61,6 +38,25 @@ func NewAdapter(logger *logrus.Logger, config *Config, service domain.Service) (
 		Handler: r,
 	}
 
+	if config.TLSClientCA != "" {
+		pem, err := ioutil.ReadFile(config.TLSClientCA)
+		if err != nil {
+			logger.WithError(err).Error("Error while reading client CA bundle!")
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			logger.Error("Client CA bundle contains no certificates!")
+			return nil, errors.New("invalid client CA bundle")
+		}
+
+		a.server.TLSConfig = &tls.Config{
+			ClientCAs:  pool,
+			ClientAuth: tls.VerifyClientCertIfGiven,
+		}
+	}
+
 	return a, nil
 }
 
@@ -68,7 +64,14 @@ func NewAdapter(logger *logrus.Logger, config *Config, service domain.Service) (
 func (a *adapter) ListenAndServe() error {
 	a.logger.WithField("address", a.config.Address).Info("Listening and serving HTTP requests.")
 
-	if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	var err error
+	if a.config.TLSCert != "" {
+		err = a.server.ListenAndServeTLS(a.config.TLSCert, a.config.TLSKey)
+	} else {
+		err = a.server.ListenAndServe()
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		a.logger.WithError(err).Error("Error listening and serving HTTP requests!")
 		return err
 	}
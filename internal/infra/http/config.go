@@ -3,8 +3,21 @@ package http
 type Config struct {
 	Address         string   `short:"a" long:"address" env:"ADDRESS" description:"Service address" required:"yes"`
 	AllowedOrigins  []string `long:"allowed-origins" env:"ALLOWED_ORIGINS" description:"Allowed origins to use CORS" env-delim:"," required:"yes"`
-	JWTPrivateKey   string   `long:"jwt-private-key" env:"JWT_PRIVATE_KEY" description:"Path to JWT private key" required:"yes"`
 	CookiePath      string   `long:"cookie-path" env:"COOKIE_PATH" description:"Cookie path" required:"yes"`
 	CookieDomain    string   `long:"cookie-domain" env:"COOKIE_DOMAIN" description:"Cookie domain" required:"yes"`
 	BaseFrontendURL string   `long:"base-frontend-url" env:"BASE_FRONTEND_URL" description:"Base frontend URL" required:"yes"`
+
+	ClientTypeHeader string `long:"client-type-header" env:"CLIENT_TYPE_HEADER" description:"Header telling web and mobile clients apart" default:"X-Client-Type"`
+	GeoCityHeader    string `long:"geo-city-header" env:"GEO_CITY_HEADER" description:"Header with the client city resolved by a trusted proxy"`
+
+	// Directory of the local blob store served at /blobs, set from the blob store config
+	BlobDir string `no-flag:"yes"`
+
+	TLSCert                 string `long:"tls-cert" env:"TLS_CERT" description:"Path to the TLS certificate, serves HTTPS when set"`
+	TLSKey                  string `long:"tls-key" env:"TLS_KEY" description:"Path to the TLS private key"`
+	TLSClientCA             string `long:"tls-client-ca" env:"TLS_CLIENT_CA" description:"Path to the CA bundle verifying service client certificates"`
+	ClientCertSubjectHeader string `long:"client-cert-subject-header" env:"CLIENT_CERT_SUBJECT_HEADER" description:"Header with the verified client certificate subject set by a trusted TLS-terminating proxy"`
+
+	DeliveryReceiptSecrets  map[string]string `long:"delivery-receipt-secret" env:"DELIVERY_RECEIPT_SECRETS" env-delim:"," description:"Delivery receipt signing secrets by provider (provider:secret)"`
+	DiscourseConnectSecrets map[string]string `long:"discourse-connect-secret" env:"DISCOURSE_CONNECT_SECRETS" env-delim:"," description:"DiscourseConnect secrets by host of the return URL (host:secret)"`
 }
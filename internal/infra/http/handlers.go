@@ -1,12 +1,27 @@
 package http
 
 import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 	"trainee-assignment-backend/internal/domain"
 	"trainee-assignment-backend/internal/infra/http/viewmodels"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/google/uuid"
 )
 
 func (a *adapter) wrap(handler func(w http.ResponseWriter, r *http.Request) error) http.Handler {
@@ -31,8 +46,10 @@ func (a *adapter) register(w http.ResponseWriter, r *http.Request) error {
 
 	d := registerRequest.Domain()
 	if d.Type == domain.RegistrationRequestTypeFinish {
+		d.Payload.(*domain.RegistrationRequestFinishPayload).ClientType = a.clientType(r)
 		d.Payload.(*domain.RegistrationRequestFinishPayload).UserAgent = r.UserAgent()
 		d.Payload.(*domain.RegistrationRequestFinishPayload).IP = r.Header.Get("X-Real-IP")
+		d.Payload.(*domain.RegistrationRequestFinishPayload).GeoCity = a.geoCity(r)
 	}
 
 	resp, err := a.service.Register(d)
@@ -44,19 +61,9 @@ func (a *adapter) register(w http.ResponseWriter, r *http.Request) error {
 	vm.Model(resp)
 
 	if d.Type == domain.RegistrationRequestTypeFinish {
-		now := time.Now().In(time.UTC)
-		maxAge := int(now.Add(60 * 24 * time.Hour).Sub(now).Seconds())
-
-		http.SetCookie(w, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    resp.RefreshToken.String(),
-			Path:     a.config.CookiePath,
-			Domain:   a.config.CookieDomain,
-			MaxAge:   maxAge,
-			Secure:   true,
-			HttpOnly: true,
-			SameSite: http.SameSiteStrictMode,
-		})
+		if err := a.setRefreshToken(w, r, resp, &vm); err != nil {
+			return jError(w, err)
+		}
 	}
 
 	return j(w, http.StatusOK, vm)
@@ -75,9 +82,17 @@ func (a *adapter) login(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	d := loginRequest.Domain()
-	if d.Type == domain.LoginRequestTypeConfirm {
+	switch d.Type {
+	case domain.LoginRequestTypeConfirm:
+		d.Payload.(*domain.LoginRequestConfirmPayload).ClientType = a.clientType(r)
 		d.Payload.(*domain.LoginRequestConfirmPayload).UserAgent = r.UserAgent()
 		d.Payload.(*domain.LoginRequestConfirmPayload).IP = r.Header.Get("X-Real-IP")
+		d.Payload.(*domain.LoginRequestConfirmPayload).GeoCity = a.geoCity(r)
+	case domain.LoginRequestTypeTOTP:
+		d.Payload.(*domain.LoginRequestTOTPPayload).ClientType = a.clientType(r)
+		d.Payload.(*domain.LoginRequestTOTPPayload).UserAgent = r.UserAgent()
+		d.Payload.(*domain.LoginRequestTOTPPayload).IP = r.Header.Get("X-Real-IP")
+		d.Payload.(*domain.LoginRequestTOTPPayload).GeoCity = a.geoCity(r)
 	}
 
 	resp, err := a.service.Login(d)
@@ -88,20 +103,11 @@ func (a *adapter) login(w http.ResponseWriter, r *http.Request) error {
 	var vm viewmodels.AuthResponse
 	vm.Model(resp)
 
-	if d.Type == domain.LoginRequestTypeConfirm {
-		now := time.Now().In(time.UTC)
-		maxAge := int(now.Add(60 * 24 * time.Hour).Sub(now).Seconds())
-
-		http.SetCookie(w, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    resp.RefreshToken.String(),
-			Path:     a.config.CookiePath,
-			Domain:   a.config.CookieDomain,
-			MaxAge:   maxAge,
-			Secure:   true,
-			HttpOnly: true,
-			SameSite: http.SameSiteStrictMode,
-		})
+	// Confirmation of a user with the second factor does not create a session yet
+	if resp.RefreshToken != uuid.Nil {
+		if err := a.setRefreshToken(w, r, resp, &vm); err != nil {
+			return jError(w, err)
+		}
 	}
 
 	return j(w, http.StatusOK, vm)
@@ -120,9 +126,19 @@ func (a *adapter) getJWT(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	d := req.Domain(r.UserAgent(), r.Header.Get("X-Real-IP"))
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		d.ClientID, _ = url.QueryUnescape(clientID)
+		d.ClientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+	d.TLSSubject = a.clientCertSubject(r)
+
+	w.Header().Set("Cache-Control", "no-store")
 
 	accessToken, refreshToken, err := a.service.GetJWT(d)
 	if err != nil {
+		if err == domain.ErrOAuthInvalidClient {
+			w.Header().Set("WWW-Authenticate", `Basic realm="jwt"`)
+		}
 		return jError(w, err)
 	}
 
@@ -137,6 +153,76 @@ func (a *adapter) getJWT(w http.ResponseWriter, r *http.Request) error {
 	return j(w, http.StatusOK, resp)
 }
 
+// setRefreshToken hands the refresh token over the way the client keeps it: mobile apps get it in the body,
+// browsers get an HttpOnly cookie along with a CSRF token readable by the frontend.
+func (a *adapter) setRefreshToken(w http.ResponseWriter, r *http.Request, resp *domain.AuthResponse, vm *viewmodels.AuthResponse) error {
+	if a.clientType(r) == domain.ClientTypeMobile {
+		return nil
+	}
+
+	vm.RefreshToken = ""
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("cannot generate a csrf token: %w", err)
+	}
+
+	maxAge := int(time.Until(resp.RefreshExpiresAt).Seconds())
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    resp.RefreshToken.String(),
+		Path:     a.config.CookiePath,
+		Domain:   a.config.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     "csrf_token",
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     "/",
+		Domain:   a.config.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return nil
+}
+
+// clientType is told by the header, service clients are identified by their credentials instead.
+func (a *adapter) clientType(r *http.Request) domain.ClientType {
+	if domain.ClientType(r.Header.Get(a.config.ClientTypeHeader)) == domain.ClientTypeMobile {
+		return domain.ClientTypeMobile
+	}
+
+	return domain.ClientTypeWeb
+}
+
+// geoCity is resolved by the proxy, it's empty when the header isn't configured.
+func (a *adapter) geoCity(r *http.Request) string {
+	if a.config.GeoCityHeader == "" {
+		return ""
+	}
+
+	return r.Header.Get(a.config.GeoCityHeader)
+}
+
+// clientCertSubject returns the subject of the verified client certificate, either presented directly
+// or forwarded by the trusted proxy.
+func (a *adapter) clientCertSubject(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.String()
+	}
+
+	if a.config.ClientCertSubjectHeader != "" {
+		return r.Header.Get(a.config.ClientCertSubjectHeader)
+	}
+
+	return ""
+}
+
 func (a *adapter) refresh(w http.ResponseWriter, r *http.Request) error {
 	var refreshRequest viewmodels.RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&refreshRequest); err != nil {
@@ -163,19 +249,9 @@ func (a *adapter) refresh(w http.ResponseWriter, r *http.Request) error {
 	var vm viewmodels.AuthResponse
 	vm.Model(resp)
 
-	now := time.Now().In(time.UTC)
-	maxAge := int(now.Add(60 * 24 * time.Hour).Sub(now).Seconds())
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    resp.RefreshToken.String(),
-		Path:     a.config.CookiePath,
-		Domain:   a.config.CookieDomain,
-		MaxAge:   maxAge,
-		Secure:   true,
-		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
-	})
+	if err := a.setRefreshToken(w, r, resp, &vm); err != nil {
+		return jError(w, err)
+	}
 
 	return j(w, http.StatusOK, vm)
 }
@@ -201,6 +277,35 @@ func (a *adapter) logout(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (a *adapter) getSessions(w http.ResponseWriter, r *http.Request) error {
+	sessions, err := a.service.GetSessions(r.Context())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	vms := make([]viewmodels.Session, len(sessions))
+	for i := range sessions {
+		vms[i].Model(sessions[i])
+	}
+
+	return j(w, http.StatusOK, vms)
+}
+
+func (a *adapter) revokeSession(w http.ResponseWriter, r *http.Request) error {
+	sessionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a session ID!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	if err := a.service.RevokeSession(r.Context(), sessionID); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func (a *adapter) getProfile(w http.ResponseWriter, r *http.Request) error {
 	user, err := a.service.GetUser(r.Context())
 	if err != nil {
@@ -210,6 +315,8 @@ func (a *adapter) getProfile(w http.ResponseWriter, r *http.Request) error {
 	var vm viewmodels.User
 	vm.Model(user)
 
+	w.Header().Set("ETag", viewmodels.ETag(user))
+	w.Header().Set("Accept-Patch", "application/merge-patch+json")
 	return j(w, http.StatusOK, vm)
 }
 
@@ -225,7 +332,123 @@ func (a *adapter) updateProfile(w http.ResponseWriter, r *http.Request) error {
 		return jError(w, domain.ErrValidationFailed)
 	}
 
-	user, err := a.service.UpdateUser(r.Context(), profileUpdateRequest.Domain())
+	user, err := a.service.UpdateUser(r.Context(), profileUpdateRequest.Domain(r.Header.Get("If-Match")))
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.User
+	vm.Model(user)
+
+	w.Header().Set("ETag", viewmodels.ETag(user))
+	return j(w, http.StatusOK, vm)
+}
+
+// deleteProfile schedules the deletion of the account, the user is logged out everywhere.
+func (a *adapter) deleteProfile(w http.ResponseWriter, r *http.Request) error {
+	if err := a.service.DeleteAccount(r.Context()); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// exportProfile sends the personal data as a JSON file or, with format=zip, as a ZIP archive of JSON files.
+func (a *adapter) exportProfile(w http.ResponseWriter, r *http.Request) error {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	export, err := a.service.ExportData(r.Context())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.DataExport
+	vm.Model(export)
+
+	name := fmt.Sprintf("export-%d-%s", export.User.ID, vm.ExportedAt.Format("20060102"))
+	if format != "zip" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, name))
+		return j(w, http.StatusOK, vm)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	for _, f := range vm.Files() {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.Name,
+			Method:   zip.Deflate,
+			Modified: vm.ExportedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot write response: %w", err)
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.Content); err != nil {
+			return fmt.Errorf("cannot write response: %w", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("cannot write response: %w", err)
+	}
+
+	return nil
+}
+
+// maxAvatarSize is the limit of an uploaded avatar file.
+const maxAvatarSize = 5 << 20
+
+// avatarContentTypes are types accepted both as declared by the client and as sniffed from the content.
+var avatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// uploadAvatar takes the image from the avatar field of a multipart form.
+func (a *adapter) uploadAvatar(w http.ResponseWriter, r *http.Request) error {
+	// Leave room for the rest of the form
+	if r.ContentLength > maxAvatarSize+1<<20 {
+		return jError(w, domain.ErrImageTooLarge)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarSize+1<<20)
+
+	file, header, err := r.FormFile("avatar")
+	if err != nil {
+		a.logger.WithError(err).Error("Error while reading an avatar!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+	defer file.Close()
+
+	contentType, _, err := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	if err != nil || !avatarContentTypes[contentType] {
+		return jError(w, domain.ErrUnsupportedImage)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, maxAvatarSize+1))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while reading an avatar!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if len(data) > maxAvatarSize {
+		return jError(w, domain.ErrImageTooLarge)
+	}
+
+	if !avatarContentTypes[http.DetectContentType(data)] {
+		return jError(w, domain.ErrUnsupportedImage)
+	}
+
+	user, err := a.service.UpdateAvatar(r.Context(), data)
 	if err != nil {
 		return jError(w, err)
 	}
@@ -233,9 +456,19 @@ func (a *adapter) updateProfile(w http.ResponseWriter, r *http.Request) error {
 	var vm viewmodels.User
 	vm.Model(user)
 
+	w.Header().Set("ETag", viewmodels.ETag(user))
 	return j(w, http.StatusOK, vm)
 }
 
+func (a *adapter) deleteAvatar(w http.ResponseWriter, r *http.Request) error {
+	if err := a.service.DeleteAvatar(r.Context()); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func (a *adapter) changeEmail(w http.ResponseWriter, r *http.Request) error {
 	var emailChangeRequest viewmodels.EmailChangeRequest
 	if err := json.NewDecoder(r.Body).Decode(&emailChangeRequest); err != nil {
@@ -256,6 +489,29 @@ func (a *adapter) changeEmail(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (a *adapter) changePhone(w http.ResponseWriter, r *http.Request) error {
+	var phoneChangeRequest viewmodels.PhoneChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&phoneChangeRequest); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := phoneChangeRequest.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a phone change request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	resp, err := a.service.ChangePhone(r.Context(), phoneChangeRequest.Domain())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.AuthResponse
+	vm.Model(resp)
+
+	return j(w, http.StatusOK, vm)
+}
+
 func (a *adapter) resendConfirmationEmail(w http.ResponseWriter, r *http.Request) error {
 	if err := a.service.ResendConfirmationEmail(r.Context()); err != nil {
 		return jError(w, err)
@@ -278,3 +534,633 @@ func (a *adapter) confirmEmail(w http.ResponseWriter, r *http.Request) error {
 	http.Redirect(w, r, a.config.BaseFrontendURL+"/personal/email-confirmation", http.StatusTemporaryRedirect)
 	return nil
 }
+
+// confirmSessionsRevocation is the "this wasn't me" link of a new device notification. Link scanners
+// open it too, so it only leads to the frontend page confirming the revocation.
+func (a *adapter) confirmSessionsRevocation(w http.ResponseWriter, r *http.Request) error {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	http.Redirect(w, r, a.config.BaseFrontendURL+"/sessions/revoke?token="+url.QueryEscape(token), http.StatusTemporaryRedirect)
+	return nil
+}
+
+// revokeSessionsByLink is posted by the confirmation page of the link.
+func (a *adapter) revokeSessionsByLink(w http.ResponseWriter, r *http.Request) error {
+	var req viewmodels.SessionsRevocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a sessions revocation request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	if err := a.service.RevokeSessionsByLink(req.Token); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// deliveryReceiptTolerance is how far the signed timestamp of a receipt may be from now, older ones are replays.
+const deliveryReceiptTolerance = 5 * time.Minute
+
+func (a *adapter) storeDeliveryReceipt(w http.ResponseWriter, r *http.Request) error {
+	provider := chi.URLParam(r, "provider")
+
+	secret, ok := a.config.DeliveryReceiptSecrets[provider]
+	if !ok {
+		a.logger.WithField("provider", provider).Error("Delivery receipt from an unknown provider!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 64*1024))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while reading request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	timestamp := r.Header.Get("X-Timestamp")
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a delivery receipt timestamp!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	if d := time.Since(time.Unix(signedAt, 0)); d > deliveryReceiptTolerance || d < -deliveryReceiptTolerance {
+		a.logger.WithField("provider", provider).Error("Delivery receipt timestamp is out of the tolerance!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	// Signature is a hex encoded HMAC-SHA256 of the timestamp (Unix seconds), a dot and the body
+	signature, err := hex.DecodeString(r.Header.Get("X-Signature"))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while decoding a delivery receipt signature!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		a.logger.WithField("provider", provider).Error("Delivery receipt signature is invalid!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	var req viewmodels.DeliveryReceiptRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a delivery receipt!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	if err := a.service.StoreDeliveryReceipt(req.Domain(provider, body)); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (a *adapter) getOTPDeliveryStatus(w http.ResponseWriter, r *http.Request) error {
+	return a.otpDeliveryStatus(w, r, a.service.GetOTPDeliveryStatus)
+}
+
+func (a *adapter) getOwnOTPDeliveryStatus(w http.ResponseWriter, r *http.Request) error {
+	return a.otpDeliveryStatus(w, r, func(requestID uuid.UUID) (*domain.OTPDeliveryStatus, error) {
+		return a.service.GetOwnOTPDeliveryStatus(r.Context(), requestID)
+	})
+}
+
+func (a *adapter) adminGetOTPDeliveryStatus(w http.ResponseWriter, r *http.Request) error {
+	return a.otpDeliveryStatus(w, r, a.service.AdminGetOTPDeliveryStatus)
+}
+
+func (a *adapter) otpDeliveryStatus(
+	w http.ResponseWriter,
+	r *http.Request,
+	get func(requestID uuid.UUID) (*domain.OTPDeliveryStatus, error),
+) error {
+	requestID, err := uuid.Parse(r.URL.Query().Get("request_id"))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a request id!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	status, err := get(requestID)
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.OTPDeliveryStatus
+	vm.Model(status)
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) stepUp(w http.ResponseWriter, r *http.Request) error {
+	var req viewmodels.StepUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a step-up request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	resp, err := a.service.StepUp(r.Context(), req.Domain())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.AuthResponse
+	vm.Model(resp)
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) enrollTOTP(w http.ResponseWriter, r *http.Request) error {
+	enrollment, err := a.service.EnrollTOTP(r.Context())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.TOTPEnrollment
+	vm.Model(enrollment)
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) confirmTOTP(w http.ResponseWriter, r *http.Request) error {
+	var req viewmodels.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a TOTP confirmation request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	recoveryCodes, err := a.service.ConfirmTOTP(r.Context(), req.Code)
+	if err != nil {
+		return jError(w, err)
+	}
+
+	return j(w, http.StatusOK, viewmodels.RecoveryCodes{
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func (a *adapter) disableTOTP(w http.ResponseWriter, r *http.Request) error {
+	var req viewmodels.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a TOTP disabling request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	if err := a.service.DisableTOTP(r.Context(), req.Code); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (a *adapter) beginWebAuthnRegistration(w http.ResponseWriter, r *http.Request) error {
+	requestID, options, err := a.service.BeginWebAuthnRegistration(r.Context())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.WebAuthnRegistrationOptions
+	vm.Model(requestID, options)
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) finishWebAuthnRegistration(w http.ResponseWriter, r *http.Request) error {
+	var req viewmodels.WebAuthnRegistrationFinishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a WebAuthn registration request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	requestID, attestation := req.Domain()
+	if err := a.service.FinishWebAuthnRegistration(r.Context(), requestID, attestation); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (a *adapter) beginWebAuthnLogin(w http.ResponseWriter, r *http.Request) error {
+	var req viewmodels.WebAuthnLoginBeginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a WebAuthn login request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	requestID, options, err := a.service.BeginWebAuthnLogin(req.Domain())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.WebAuthnLoginOptions
+	vm.Model(requestID, options)
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) finishWebAuthnLogin(w http.ResponseWriter, r *http.Request) error {
+	var req viewmodels.WebAuthnLoginFinishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a WebAuthn login request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	d := req.Domain(r.UserAgent(), r.Header.Get("X-Real-IP"))
+	d.ClientType = a.clientType(r)
+	d.GeoCity = a.geoCity(r)
+
+	resp, err := a.service.FinishWebAuthnLogin(d)
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.AuthResponse
+	vm.Model(resp)
+
+	if err := a.setRefreshToken(w, r, resp, &vm); err != nil {
+		return jError(w, err)
+	}
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) getJWKS(w http.ResponseWriter, r *http.Request) error {
+	keys, err := a.service.GetJWKS()
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.JSONWebKeySet
+	vm.Model(keys)
+
+	// New keys are published long before use, so the set may be cached
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) getOIDCConfiguration(w http.ResponseWriter, r *http.Request) error {
+	keys, err := a.service.GetJWKS()
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.OIDCConfiguration
+	vm.Model(a.service.GetOIDCIssuer(), keys)
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	return j(w, http.StatusOK, vm)
+}
+
+// authorizeOIDC returns errors to the client by the redirect URI once the client and the URI are known.
+func (a *adapter) authorizeOIDC(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	req := viewmodels.OIDCAuthorizationRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		Nonce:               q.Get("nonce"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating an authorization request!")
+		return jError(w, domain.ErrOAuthInvalidRequest)
+	}
+
+	redirectURI, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a redirect URI!")
+		return jError(w, domain.ErrOAuthInvalidRedirectURI)
+	}
+
+	params := redirectURI.Query()
+
+	code, err := a.service.AuthorizeOIDC(r.Context(), req.Domain())
+	switch err {
+	case nil:
+		params.Set("code", code)
+	case domain.ErrOAuthInvalidRequest, domain.ErrOAuthInvalidScope, domain.ErrOAuthUnsupportedResponse,
+		domain.ErrOAuthUnauthorizedClient, domain.ErrOAuthAccessDenied:
+		params.Set("error", err.Error())
+	case domain.ErrOAuthInvalidClient, domain.ErrOAuthInvalidRedirectURI, domain.ErrInvalidInputData:
+		return jError(w, err)
+	default:
+		params.Set("error", "server_error")
+	}
+
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	redirectURI.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+	return nil
+}
+
+func (a *adapter) exchangeOIDCCode(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		a.logger.WithError(err).Error("Error while parsing a token request!")
+		return jError(w, domain.ErrOAuthInvalidRequest)
+	}
+
+	req := viewmodels.OIDCTokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+
+	// Credentials of HTTP Basic authentication are form-encoded (RFC 6749, section 2.3.1)
+	basic := false
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		basic = true
+		req.ClientID, _ = url.QueryUnescape(clientID)
+		req.ClientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a token request!")
+		return jError(w, domain.ErrOAuthInvalidRequest)
+	}
+
+	resp, err := a.service.ExchangeOIDCCode(req.Domain())
+	if err != nil {
+		if err == domain.ErrOAuthInvalidClient && basic {
+			w.Header().Set("WWW-Authenticate", "Basic")
+		}
+
+		return jError(w, err)
+	}
+
+	var vm viewmodels.OIDCTokenResponse
+	vm.Model(resp)
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) getOIDCUserInfo(w http.ResponseWriter, r *http.Request) error {
+	token := jwtauth.TokenFromHeader(r)
+	if token == "" {
+		w.Header().Add("WWW-Authenticate", "Bearer")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	user, scopes, err := a.service.GetOIDCUserInfo(token)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			w.Header().Add("WWW-Authenticate", `Bearer error="invalid_token"`)
+		case domain.ErrOAuthInsufficientScope:
+			w.Header().Add("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		}
+
+		return jError(w, err)
+	}
+
+	var vm viewmodels.OIDCUserInfo
+	vm.Model(user, scopes)
+
+	return j(w, http.StatusOK, vm)
+}
+
+// discourseConnect completes a DiscourseConnect login. The consumer is identified by the host of the return URL,
+// the signed payload is trusted only after its signature is checked with the consumer secret.
+func (a *adapter) discourseConnect(w http.ResponseWriter, r *http.Request) error {
+	sso := r.URL.Query().Get("sso")
+
+	decoded, err := base64.StdEncoding.DecodeString(sso)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while decoding a DiscourseConnect payload!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	payload, err := url.ParseQuery(string(decoded))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a DiscourseConnect payload!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	var req viewmodels.DiscourseConnectRequest
+	req.Parse(payload)
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a DiscourseConnect payload!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	returnURL, err := url.Parse(req.ReturnSSOURL)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a DiscourseConnect return URL!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	secret, ok := a.config.DiscourseConnectSecrets[returnURL.Host]
+	if !ok {
+		a.logger.WithField("host", returnURL.Host).Error("DiscourseConnect request from an unknown consumer!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	// Signature is a hex encoded HMAC-SHA256 of the base64 payload
+	signature, err := hex.DecodeString(r.URL.Query().Get("sig"))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while decoding a DiscourseConnect signature!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	if !hmac.Equal(signature, discourseConnectSignature(secret, sso)) {
+		a.logger.WithField("host", returnURL.Host).Error("DiscourseConnect signature is invalid!")
+		return jError(w, domain.ErrUnauthorized)
+	}
+
+	user, err := a.service.GetUser(r.Context())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	// Consumers identify accounts by email
+	if user.Email == nil {
+		return jError(w, domain.ErrEmailRequired)
+	}
+
+	var resp viewmodels.DiscourseConnectResponse
+	resp.Model(req.Nonce, user)
+
+	respPayload := base64.StdEncoding.EncodeToString([]byte(resp.Values().Encode()))
+
+	params := returnURL.Query()
+	params.Set("sso", respPayload)
+	params.Set("sig", hex.EncodeToString(discourseConnectSignature(secret, respPayload)))
+	returnURL.RawQuery = params.Encode()
+
+	http.Redirect(w, r, returnURL.String(), http.StatusFound)
+	return nil
+}
+
+func discourseConnectSignature(secret, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	return mac.Sum(nil)
+}
+
+func (a *adapter) searchUsers(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	req := viewmodels.UserSearchRequest{
+		Query: q.Get("query"),
+		Limit: 20,
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			a.logger.WithError(err).Error("Error while parsing a limit!")
+			return jError(w, domain.ErrValidationFailed)
+		}
+
+		req.Limit = limit
+	}
+
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil {
+			a.logger.WithError(err).Error("Error while parsing an offset!")
+			return jError(w, domain.ErrValidationFailed)
+		}
+
+		req.Offset = offset
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a user search request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	users, err := a.service.AdminSearchUsers(req.Domain())
+	if err != nil {
+		return jError(w, err)
+	}
+
+	vms := make([]viewmodels.AdminUser, len(users))
+	for i := range users {
+		vms[i].Model(users[i])
+	}
+
+	return j(w, http.StatusOK, vms)
+}
+
+func (a *adapter) getUser(w http.ResponseWriter, r *http.Request) error {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a user ID!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	user, err := a.service.AdminGetUser(userID)
+	if err != nil {
+		return jError(w, err)
+	}
+
+	var vm viewmodels.AdminUser
+	vm.Model(user)
+
+	return j(w, http.StatusOK, vm)
+}
+
+func (a *adapter) revokeUserSessions(w http.ResponseWriter, r *http.Request) error {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a user ID!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	if err := a.service.AdminRevokeSessions(r.Context(), userID); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (a *adapter) setUserState(w http.ResponseWriter, r *http.Request) error {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a user ID!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	var req viewmodels.UserStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.WithError(err).Error("Error while decoding request body!")
+		return jError(w, domain.ErrInvalidInputData)
+	}
+
+	if err := req.Validate(); err != nil {
+		a.logger.WithError(err).Error("Error while validating a user state request!")
+		return jError(w, domain.ErrValidationFailed)
+	}
+
+	if err := a.service.AdminSetUserState(r.Context(), userID, req.Domain()); err != nil {
+		return jError(w, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -2,51 +2,69 @@ package http
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"strconv"
+	"strings"
 	"trainee-assignment-backend/internal/domain"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/go-chi/jwtauth"
 )
 
 func (a *adapter) accessTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token, err := jwtauth.VerifyRequest(a.jwtAuth, r, jwtauth.TokenFromHeader)
-		if err != nil {
-			a.logger.WithError(err).Error("Error while verifying an access token!")
+		token := jwtauth.TokenFromHeader(r)
+		if token == "" {
+			a.logger.Error("Request is without an access token!")
 
 			w.Header().Add("WWW-Authenticate", "Bearer")
 			_ = jError(w, domain.ErrUnauthorized)
 			return
 		}
 
-		sub, ok := token.Claims.(jwt.MapClaims)["sub"]
-		if !ok {
-			a.logger.Error("Token is without 'sub' field!")
-			_ = jError(w, domain.ErrUnauthorized)
-			return
-		}
+		claims, err := a.service.ParseAccessToken(token)
+		if err != nil {
+			a.logger.WithError(err).Error("Error while verifying an access token!")
 
-		subStr, ok := sub.(string)
-		if !ok {
-			a.logger.Error("'sub' field is not a string!")
-			_ = jError(w, domain.ErrUnauthorized)
+			w.Header().Add("WWW-Authenticate", "Bearer")
+			_ = jError(w, err)
 			return
 		}
 
-		id, err := strconv.Atoi(subStr)
-		if err != nil {
-			a.logger.WithError(err).Error("Error while converting string into int!")
+		// Tokens issued to OAuth clients don't grant access to the API
+		if claims.ClientID != "" {
+			a.logger.WithField("client_id", claims.ClientID).Error("Access token of an OAuth client!")
+
+			w.Header().Add("WWW-Authenticate", "Bearer")
 			_ = jError(w, domain.ErrUnauthorized)
 			return
 		}
 
-		w.Header().Set("User-ID", strconv.Itoa(id))
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), domain.ContextUserID, id)))
+		w.Header().Set("User-ID", strconv.Itoa(claims.UserID))
+		ctx := context.WithValue(r.Context(), domain.ContextUserID, claims.UserID)
+		ctx = context.WithValue(ctx, domain.ContextRoles, claims.Roles)
+		ctx = context.WithValue(ctx, domain.ContextSessionID, claims.SessionID)
+		ctx = context.WithValue(ctx, domain.ContextAuthTime, claims.AuthTime)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// requirePermission lets through users whose roles grant the permission, use after accessTokenMiddleware.
+func (a *adapter) requirePermission(permission domain.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := a.service.Authorize(r.Context(), permission); err != nil {
+				a.logger.WithError(err).WithField("permission", permission).Error("Error while authorizing a request!")
+
+				_ = jError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (a *adapter) refreshTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		redirect := false
@@ -54,31 +72,60 @@ func (a *adapter) refreshTokenMiddleware(next http.Handler) http.Handler {
 			redirect = true
 		}
 
-		cookie, err := r.Cookie("refresh_token")
-		if err != nil {
-			a.logger.WithError(err).Error("Error while reading a cookie!")
-			if !redirect {
-				_ = jError(w, domain.ErrUnauthorized)
-			} else {
-				http.Redirect(w, r, a.config.BaseFrontendURL+"/auth?"+r.URL.RawQuery, http.StatusTemporaryRedirect)
-			}
+		a.serveWithRefreshToken(w, r, next, redirect)
+	})
+}
+
+// loginRedirectMiddleware sends users without a session to the frontend auth page with the original query.
+func (a *adapter) loginRedirectMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.serveWithRefreshToken(w, r, next, true)
+	})
+}
 
+func (a *adapter) serveWithRefreshToken(w http.ResponseWriter, r *http.Request, next http.Handler, redirect bool) {
+	token, err := a.refreshToken(r)
+	if err == nil {
+		var userID int
+		userID, err = a.service.ValidateRefreshToken(token, r.UserAgent(), r.Header.Get("X-Real-IP"))
+		if err == nil {
+			ctx := context.WithValue(r.Context(), domain.ContextUserID, userID)
+			ctx = context.WithValue(ctx, domain.ContextRefreshToken, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
+	}
 
-		userID, err := a.service.ValidateRefreshToken(cookie.Value)
-		if err != nil {
-			if !redirect {
-				_ = jError(w, err)
-			} else {
-				http.Redirect(w, r, a.config.BaseFrontendURL+"/auth?"+r.URL.RawQuery, http.StatusTemporaryRedirect)
-			}
+	a.logger.WithError(err).Error("Error while reading a refresh token!")
+	if !redirect {
+		_ = jError(w, err)
+	} else {
+		http.Redirect(w, r, a.config.BaseFrontendURL+"/auth?"+r.URL.RawQuery, http.StatusTemporaryRedirect)
+	}
+}
 
-			return
-		}
+// refreshToken is taken from the "Authorization: Refresh <token>" header sent by mobile apps or from the cookie.
+// Cookies are sent by the browser on their own, so state changing requests must also repeat the CSRF token.
+func (a *adapter) refreshToken(r *http.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); len(header) > 8 && strings.EqualFold(header[:8], "refresh ") {
+		return header[8:], nil
+	}
 
-		ctx := context.WithValue(r.Context(), domain.ContextUserID, userID)
-		ctx = context.WithValue(ctx, domain.ContextRefreshToken, cookie.Value)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	cookie, err := r.Cookie("refresh_token")
+	if err != nil {
+		return "", domain.ErrUnauthorized
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return cookie.Value, nil
+	}
+
+	csrf, err := r.Cookie("csrf_token")
+	if err != nil || csrf.Value == "" ||
+		subtle.ConstantTimeCompare([]byte(csrf.Value), []byte(r.Header.Get("X-CSRF-Token"))) != 1 {
+		return "", domain.ErrInvalidCSRFToken
+	}
+
+	return cookie.Value, nil
 }
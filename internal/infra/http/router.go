@@ -2,10 +2,12 @@ package http
 
 import (
 	"net/http"
+	"os"
+	"strings"
+	"trainee-assignment-backend/internal/domain"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
-	"github.com/go-chi/jwtauth"
 	"github.com/rs/cors"
 )
 
@@ -20,10 +22,19 @@ func (a *adapter) newRouter() (http.Handler, error) {
 
 	c := cors.New(cors.Options{
 		AllowedOrigins:   a.config.AllowedOrigins,
+		AllowedHeaders:   []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization", "X-CSRF-Token", "If-Match", a.config.ClientTypeHeader},
+		ExposedHeaders:   []string{"ETag"},
 		AllowCredentials: true,
 	})
 	r.Use(c.Handler)
 
+	r.Method(http.MethodGet, "/.well-known/jwks.json", a.wrap(a.getJWKS))
+	r.Method(http.MethodGet, "/.well-known/openid-configuration", a.wrap(a.getOIDCConfiguration))
+
+	if a.config.BlobDir != "" {
+		r.Handle("/blobs/*", http.StripPrefix("/blobs/", newBlobHandler(a.config.BlobDir)))
+	}
+
 	r.Route("/api", func(r chi.Router) {
 		r.Route("/v1", func(r chi.Router) {
 			r.Method(http.MethodPost, "/register", a.wrap(a.register))
@@ -31,25 +42,115 @@ func (a *adapter) newRouter() (http.Handler, error) {
 
 			r.Method(http.MethodPost, "/jwt", a.wrap(a.getJWT))
 
+			r.Route("/oidc", func(r chi.Router) {
+				r.With(a.loginRedirectMiddleware).Method(http.MethodGet, "/authorize", a.wrap(a.authorizeOIDC))
+				r.Method(http.MethodPost, "/token", a.wrap(a.exchangeOIDCCode))
+				r.Method(http.MethodGet, "/userinfo", a.wrap(a.getOIDCUserInfo))
+				r.Method(http.MethodPost, "/userinfo", a.wrap(a.getOIDCUserInfo))
+			})
+
+			r.Method(http.MethodPost, "/webauthn/login/begin", a.wrap(a.beginWebAuthnLogin))
+			r.Method(http.MethodPost, "/webauthn/login/finish", a.wrap(a.finishWebAuthnLogin))
+
+			r.Method(http.MethodGet, "/otp/status", a.wrap(a.getOTPDeliveryStatus))
+			r.Method(http.MethodPost, "/webhooks/sms/{provider}", a.wrap(a.storeDeliveryReceipt))
+
 			r.Group(func(r chi.Router) {
 				r.Use(a.refreshTokenMiddleware)
 				r.Method(http.MethodPost, "/refresh", a.wrap(a.refresh))
 				r.Method(http.MethodPost, "/logout", a.wrap(a.logout))
+				r.Method(http.MethodGet, "/sso/discourse", a.wrap(a.discourseConnect))
+
+				r.Method(http.MethodGet, "/sessions", a.wrap(a.getSessions))
+				r.Method(http.MethodDelete, "/sessions/{id:[0-9]+}", a.wrap(a.revokeSession))
 			})
 
+			r.Method(http.MethodGet, "/sessions/revoke", a.wrap(a.confirmSessionsRevocation))
+			r.Method(http.MethodPost, "/sessions/revoke", a.wrap(a.revokeSessionsByLink))
 			r.Method(http.MethodGet, "/profile/email/confirm", a.wrap(a.confirmEmail))
 
 			r.Group(func(r chi.Router) {
-				r.Use(jwtauth.Verifier(a.jwtAuth))
 				r.Use(a.accessTokenMiddleware)
 
 				r.Method(http.MethodGet, "/profile", a.wrap(a.getProfile))
 				r.Method(http.MethodPatch, "/profile", a.wrap(a.updateProfile))
+				r.Method(http.MethodDelete, "/profile", a.wrap(a.deleteProfile))
+				r.Method(http.MethodGet, "/profile/export", a.wrap(a.exportProfile))
+				r.Method(http.MethodPut, "/profile/avatar", a.wrap(a.uploadAvatar))
+				r.Method(http.MethodDelete, "/profile/avatar", a.wrap(a.deleteAvatar))
 				r.Method(http.MethodPost, "/profile/email", a.wrap(a.changeEmail))
 				r.Method(http.MethodPost, "/profile/email/resend", a.wrap(a.resendConfirmationEmail))
+				r.Method(http.MethodPost, "/profile/phone", a.wrap(a.changePhone))
+				r.Method(http.MethodPost, "/profile/step-up", a.wrap(a.stepUp))
+				r.Method(http.MethodGet, "/profile/otp/status", a.wrap(a.getOwnOTPDeliveryStatus))
+
+				r.Method(http.MethodPost, "/profile/2fa", a.wrap(a.enrollTOTP))
+				r.Method(http.MethodPost, "/profile/2fa/confirm", a.wrap(a.confirmTOTP))
+				r.Method(http.MethodDelete, "/profile/2fa", a.wrap(a.disableTOTP))
+
+				r.Method(http.MethodPost, "/webauthn/register/begin", a.wrap(a.beginWebAuthnRegistration))
+				r.Method(http.MethodPost, "/webauthn/register/finish", a.wrap(a.finishWebAuthnRegistration))
+			})
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(a.accessTokenMiddleware)
+
+				r.With(a.requirePermission(domain.PermissionUsersRead)).
+					Method(http.MethodGet, "/users", a.wrap(a.searchUsers))
+				r.With(a.requirePermission(domain.PermissionUsersRead)).
+					Method(http.MethodGet, "/users/{id:[0-9]+}", a.wrap(a.getUser))
+				r.With(a.requirePermission(domain.PermissionSessionsRevoke)).
+					Method(http.MethodDelete, "/users/{id:[0-9]+}/sessions", a.wrap(a.revokeUserSessions))
+				r.With(a.requirePermission(domain.PermissionUsersRestrict)).
+					Method(http.MethodPut, "/users/{id:[0-9]+}/state", a.wrap(a.setUserState))
+				r.With(a.requirePermission(domain.PermissionUsersRead)).
+					Method(http.MethodGet, "/otp/status", a.wrap(a.adminGetOTPDeliveryStatus))
 			})
 		})
 	})
 
 	return r, nil
 }
+
+// newBlobHandler serves files of the local blob store only: directories aren't listed
+// and unfinished uploads aren't exposed.
+func newBlobHandler(dir string) http.Handler {
+	files := http.FileServer(blobFileSystem{http.Dir(dir)})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		files.ServeHTTP(w, r)
+	})
+}
+
+type blobFileSystem struct {
+	fs http.FileSystem
+}
+
+func (b blobFileSystem) Open(name string) (http.File, error) {
+	if strings.HasSuffix(name, ".tmp") {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := b.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if !info.Mode().IsRegular() {
+		_ = f.Close()
+		return nil, os.ErrNotExist
+	}
+
+	return f, nil
+}
@@ -47,6 +47,12 @@ func jError(w http.ResponseWriter, err error) error {
 	case domain.ErrUnauthorized:
 		code = http.StatusUnauthorized
 		localizedError = "Вы не авторизованы!"
+	case domain.ErrInvalidCSRFToken:
+		code = http.StatusForbidden
+		localizedError = "Запрос отклонён! Обновите страницу."
+	case domain.ErrRefreshTokenReused:
+		code = http.StatusUnauthorized
+		localizedError = "Сеанс завершён в целях безопасности! Войдите заново."
 	case domain.ErrInvalidInputData:
 		code = http.StatusBadRequest
 		localizedError = "Неверный запрос!"
@@ -56,9 +62,30 @@ func jError(w http.ResponseWriter, err error) error {
 	case domain.ErrUserAlreadyExists:
 		code = http.StatusBadRequest
 		localizedError = "Пользователь с данным номером телефона уже зарегистрирован!"
+	case domain.ErrProfileVersionMismatch:
+		code = http.StatusPreconditionFailed
+		localizedError = "Профиль был изменён! Обновите страницу и повторите попытку."
+	case domain.ErrSamePhone:
+		code = http.StatusBadRequest
+		localizedError = "Новый номер телефона совпадает с текущим!"
+	case domain.ErrUnsupportedImage:
+		code = http.StatusUnsupportedMediaType
+		localizedError = "Загрузите изображение в формате JPEG, PNG или GIF!"
+	case domain.ErrImageTooLarge:
+		code = http.StatusRequestEntityTooLarge
+		localizedError = "Слишком большое изображение!"
+	case domain.ErrAvatarNotFound:
+		code = http.StatusNotFound
+		localizedError = "Аватар не загружен!"
+	case domain.ErrInvalidPhoneChangeOrder:
+		code = http.StatusBadRequest
+		localizedError = "Неверный порядок смены номера телефона!"
 	case domain.ErrNonexistentOrExpiredCode:
 		code = http.StatusBadRequest
 		localizedError = "Данный одноразовый код не существует или его срок действия истёк!"
+	case domain.ErrNonexistentOrExpiredToken:
+		code = http.StatusBadRequest
+		localizedError = "Ссылка недействительна или её срок действия истёк!"
 	case domain.ErrInvalidOTPCode:
 		code = http.StatusBadRequest
 		localizedError = "Неверный одноразовый код!"
@@ -71,6 +98,79 @@ func jError(w http.ResponseWriter, err error) error {
 	case domain.ErrOTPAttemptsExceeded:
 		code = http.StatusTooManyRequests
 		localizedError = "Лимит на проверку СМС кода исчепан! Попробуйте позже."
+	case domain.ErrOTPChannelUnavailable:
+		code = http.StatusBadRequest
+		localizedError = "Выбранный способ отправки кода недоступен!"
+	case domain.ErrTOTPAlreadyEnabled:
+		code = http.StatusConflict
+		localizedError = "Двухфакторная аутентификация уже включена!"
+	case domain.ErrTOTPNotEnabled:
+		code = http.StatusBadRequest
+		localizedError = "Двухфакторная аутентификация не включена!"
+	case domain.ErrInvalidTOTPCode:
+		code = http.StatusBadRequest
+		localizedError = "Неверный код аутентификатора!"
+	case domain.ErrTOTPCodeReused:
+		code = http.StatusBadRequest
+		localizedError = "Код аутентификатора уже использован! Дождитесь следующего."
+	case domain.ErrTOTPAttemptsExceeded:
+		code = http.StatusTooManyRequests
+		localizedError = "Слишком много неверных кодов аутентификатора! Попробуйте позже."
+	case domain.ErrWebAuthnVerificationFailed:
+		code = http.StatusUnauthorized
+		localizedError = "Не удалось подтвердить ключ доступа!"
+	case domain.ErrWebAuthnCredentialNotFound:
+		code = http.StatusNotFound
+		localizedError = "Ключ доступа не найден!"
+	case domain.ErrWebAuthnCredentialRegistered:
+		code = http.StatusConflict
+		localizedError = "Ключ доступа уже зарегистрирован!"
+	case domain.ErrForbidden:
+		code = http.StatusForbidden
+		localizedError = "Недостаточно прав!"
+	case domain.ErrStepUpRequired:
+		code = http.StatusForbidden
+		localizedError = "Подтвердите действие одноразовым кодом!"
+	case domain.ErrUserNotFound:
+		code = http.StatusNotFound
+		localizedError = "Пользователь не найден!"
+	case domain.ErrUserSuspended:
+		code = http.StatusForbidden
+		localizedError = "Учётная запись временно заблокирована!"
+	case domain.ErrUserBanned:
+		code = http.StatusForbidden
+		localizedError = "Учётная запись заблокирована!"
+	case domain.ErrUserPendingDeletion:
+		code = http.StatusForbidden
+		localizedError = "Учётная запись будет удалена!"
+	case domain.ErrSessionNotFound:
+		code = http.StatusNotFound
+		localizedError = "Сеанс не найден!"
+	case domain.ErrCurrentSession:
+		code = http.StatusBadRequest
+		localizedError = "Текущий сеанс завершается выходом из аккаунта!"
+	case domain.ErrEmailRequired:
+		code = http.StatusBadRequest
+		localizedError = "Укажите email в профиле!"
+	case domain.ErrOAuthInvalidRequest, domain.ErrOAuthInvalidGrant, domain.ErrOAuthInvalidScope,
+		domain.ErrOAuthUnauthorizedClient, domain.ErrOAuthUnsupportedGrantType, domain.ErrOAuthUnsupportedResponse:
+		code = http.StatusBadRequest
+		localizedError = "Неверный запрос авторизации!"
+	case domain.ErrOAuthInvalidRedirectURI:
+		code = http.StatusBadRequest
+		localizedError = "Неверный адрес возврата!"
+	case domain.ErrOAuthInvalidClient:
+		code = http.StatusUnauthorized
+		localizedError = "Неизвестное приложение!"
+	case domain.ErrOAuthAccessDenied, domain.ErrOAuthInsufficientScope:
+		code = http.StatusForbidden
+		localizedError = "Доступ запрещён!"
+	case domain.ErrSMSDeliveryFailed:
+		code = http.StatusServiceUnavailable
+		localizedError = "Не удалось отправить СМС! Пожалуйста, попробуйте позже."
+	case domain.ErrOutboundMessageNotFound:
+		code = http.StatusNotFound
+		localizedError = "Сообщение не найдено!"
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,113 @@
+package viewmodels
+
+import (
+	"errors"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+)
+
+// AdminUser is a profile of a user as seen by the support staff.
+type AdminUser struct {
+	User
+	Email          string   `json:"email"`
+	EmailConfirmed bool     `json:"email_confirmed"`
+	Roles          []string `json:"roles"`
+
+	State          string     `json:"state"`
+	StateReason    string     `json:"state_reason,omitempty"`
+	StateExpiresAt *time.Time `json:"state_expires_at,omitempty"`
+}
+
+func (m *AdminUser) Model(d *domain.User) {
+	m.User.Model(d)
+	if d.Email != nil {
+		m.Email = *d.Email
+	}
+	m.EmailConfirmed = d.Status.IsEmailConfirmed()
+	m.Roles = d.Roles
+	if m.Roles == nil {
+		m.Roles = []string{}
+	}
+
+	m.State = string(d.State())
+	if d.State() != domain.AccountStateActive {
+		if d.StatusReason != nil {
+			m.StateReason = *d.StatusReason
+		}
+		m.StateExpiresAt = d.StatusExpiresAt
+	}
+}
+
+type UserStateRequest struct {
+	State     string     `json:"state"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (r UserStateRequest) Validate() error {
+	if err := validation.ValidateStruct(
+		&r,
+		validation.Field(&r.State, validation.Required, validation.In(
+			string(domain.AccountStateActive),
+			string(domain.AccountStateSuspended),
+			string(domain.AccountStateBanned),
+			string(domain.AccountStatePendingDeletion),
+		)),
+		validation.Field(&r.Reason, validation.Length(0, 500)),
+	); err != nil {
+		return err
+	}
+
+	switch {
+	case r.State == string(domain.AccountStateActive):
+		if r.Reason != "" || r.ExpiresAt != nil {
+			return errors.New("active state has neither a reason nor an expiry")
+		}
+	case r.Reason == "":
+		return errors.New("reason is required")
+	case r.State == string(domain.AccountStatePendingDeletion) && r.ExpiresAt == nil:
+		return errors.New("expires_at is required")
+	case r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()):
+		return errors.New("expires_at must be in the future")
+	}
+
+	return nil
+}
+
+func (r *UserStateRequest) Domain() *domain.UserStateRequest {
+	d := &domain.UserStateRequest{
+		State:  domain.AccountState(r.State),
+		Reason: r.Reason,
+	}
+	if r.ExpiresAt != nil {
+		expiresAt := r.ExpiresAt.In(time.UTC)
+		d.ExpiresAt = &expiresAt
+	}
+
+	return d
+}
+
+type UserSearchRequest struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+func (r UserSearchRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.Query, validation.Length(0, 100)),
+		validation.Field(&r.Limit, validation.Required, validation.Min(1), validation.Max(100)),
+		validation.Field(&r.Offset, validation.Min(0)),
+	)
+}
+
+func (r *UserSearchRequest) Domain() *domain.UserSearchRequest {
+	return &domain.UserSearchRequest{
+		Query:  r.Query,
+		Limit:  r.Limit,
+		Offset: r.Offset,
+	}
+}
@@ -0,0 +1,65 @@
+package viewmodels
+
+import (
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+)
+
+type DeliveryReceiptRequest struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`
+	ErrorCode string `json:"error_code"`
+}
+
+func (r DeliveryReceiptRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.MessageID, validation.Required),
+		validation.Field(
+			&r.Status,
+			validation.Required,
+			validation.In(
+				string(domain.DeliveryStatusEnroute),
+				string(domain.DeliveryStatusDelivered),
+				string(domain.DeliveryStatusUndelivered),
+				string(domain.DeliveryStatusExpired),
+				string(domain.DeliveryStatusRejected),
+				string(domain.DeliveryStatusUnknown),
+			),
+		),
+	)
+}
+
+func (r *DeliveryReceiptRequest) Domain(provider string, payload []byte) *domain.DeliveryReceipt {
+	return &domain.DeliveryReceipt{
+		Provider:          provider,
+		ProviderMessageID: r.MessageID,
+		Status:            domain.DeliveryStatus(r.Status),
+		ErrorCode:         r.ErrorCode,
+		Payload:           payload,
+	}
+}
+
+type OTPDeliveryStatus struct {
+	MessageStatus  string     `json:"message_status"`
+	DeliveryStatus string     `json:"delivery_status"`
+	Provider       string     `json:"provider,omitempty"`
+	ErrorCode      string     `json:"error_code,omitempty"`
+	SentAt         *time.Time `json:"sent_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (m *OTPDeliveryStatus) Model(d *domain.OTPDeliveryStatus) {
+	m.MessageStatus = string(d.MessageStatus)
+	m.DeliveryStatus = string(d.DeliveryStatus)
+	if d.Provider != nil {
+		m.Provider = *d.Provider
+	}
+	if d.ErrorCode != nil {
+		m.ErrorCode = *d.ErrorCode
+	}
+	m.SentAt = d.SentAt
+	m.UpdatedAt = d.UpdatedAt
+}
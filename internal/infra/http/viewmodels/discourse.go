@@ -0,0 +1,71 @@
+package viewmodels
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"trainee-assignment-backend/internal/domain"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"github.com/go-ozzo/ozzo-validation/v3/is"
+)
+
+// DiscourseConnectRequest is the decoded `sso` payload sent by a DiscourseConnect consumer.
+type DiscourseConnectRequest struct {
+	Nonce        string
+	ReturnSSOURL string
+}
+
+func (r *DiscourseConnectRequest) Parse(payload url.Values) {
+	r.Nonce = payload.Get("nonce")
+	r.ReturnSSOURL = payload.Get("return_sso_url")
+}
+
+func (r DiscourseConnectRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.Nonce, validation.Required),
+		validation.Field(&r.ReturnSSOURL, validation.Required, is.URL),
+	)
+}
+
+// DiscourseConnectResponse is the payload returned to the consumer, an unconfirmed email
+// makes the consumer send its own activation email.
+type DiscourseConnectResponse struct {
+	Nonce             string
+	Email             string
+	ExternalID        string
+	Name              string
+	RequireActivation bool
+}
+
+func (m *DiscourseConnectResponse) Model(nonce string, d *domain.User) {
+	m.Nonce = nonce
+	m.ExternalID = strconv.Itoa(d.ID)
+	m.RequireActivation = !d.Status.IsEmailConfirmed()
+
+	if d.Email != nil {
+		m.Email = *d.Email
+	}
+
+	var name []string
+	for _, part := range []*string{d.FirstName, d.LastName} {
+		if part != nil && *part != "" {
+			name = append(name, *part)
+		}
+	}
+	m.Name = strings.Join(name, " ")
+}
+
+func (m *DiscourseConnectResponse) Values() url.Values {
+	v := url.Values{}
+	v.Set("nonce", m.Nonce)
+	v.Set("email", m.Email)
+	v.Set("external_id", m.ExternalID)
+	if m.Name != "" {
+		v.Set("name", m.Name)
+	}
+	v.Set("require_activation", strconv.FormatBool(m.RequireActivation))
+
+	return v
+}
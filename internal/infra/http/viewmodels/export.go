@@ -0,0 +1,129 @@
+package viewmodels
+
+import (
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+// DataExport is the archive of the personal data of the user.
+type DataExport struct {
+	User         AdminUser           `json:"user"`
+	Sessions     []ExportSession     `json:"sessions"`
+	KnownDevices []ExportKnownDevice `json:"known_devices"`
+	AuditEvents  []ExportAuditEvent  `json:"audit_events"`
+	Messages     []ExportMessage     `json:"messages"`
+	ExportedAt   time.Time           `json:"exported_at"`
+}
+
+func (m *DataExport) Model(d *domain.DataExport) {
+	m.User.Model(d.User)
+
+	m.Sessions = make([]ExportSession, len(d.Sessions))
+	for i := range d.Sessions {
+		m.Sessions[i].Model(d.Sessions[i])
+	}
+
+	m.KnownDevices = make([]ExportKnownDevice, len(d.KnownDevices))
+	for i := range d.KnownDevices {
+		m.KnownDevices[i].Model(d.KnownDevices[i])
+	}
+
+	m.AuditEvents = make([]ExportAuditEvent, len(d.AuditEvents))
+	for i := range d.AuditEvents {
+		m.AuditEvents[i].Model(d.AuditEvents[i])
+	}
+
+	m.Messages = make([]ExportMessage, len(d.Messages))
+	for i := range d.Messages {
+		m.Messages[i].Model(d.Messages[i])
+	}
+
+	m.ExportedAt = time.Now().In(time.UTC)
+}
+
+// Files split the export for a ZIP archive, in the order of the archive.
+func (m *DataExport) Files() []ExportFile {
+	return []ExportFile{
+		{Name: "user.json", Content: m.User},
+		{Name: "sessions.json", Content: m.Sessions},
+		{Name: "known_devices.json", Content: m.KnownDevices},
+		{Name: "audit_events.json", Content: m.AuditEvents},
+		{Name: "messages.json", Content: m.Messages},
+	}
+}
+
+type ExportFile struct {
+	Name    string
+	Content interface{}
+}
+
+type ExportSession struct {
+	Session
+	UserAgent   string     `json:"user_agent"`
+	Fingerprint string     `json:"fingerprint"`
+	AuthTime    time.Time  `json:"auth_time"`
+	RotatedAt   *time.Time `json:"rotated_at"`
+}
+
+func (m *ExportSession) Model(d *domain.RefreshSession) {
+	m.Session.Model(d)
+	m.UserAgent = d.UserAgent
+	m.Fingerprint = d.Fingerprint
+	m.AuthTime = d.AuthTime
+	m.RotatedAt = d.RotatedAt
+}
+
+type ExportKnownDevice struct {
+	Fingerprint string    `json:"fingerprint"`
+	UserAgent   string    `json:"user_agent"`
+	IP          string    `json:"ip"`
+	City        string    `json:"city"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+func (m *ExportKnownDevice) Model(d *domain.KnownDevice) {
+	m.Fingerprint = d.Fingerprint
+	m.UserAgent = d.UserAgent
+	m.IP = d.IP
+	m.City = d.City
+	m.FirstSeenAt = d.FirstSeenAt
+	m.LastSeenAt = d.LastSeenAt
+}
+
+type ExportAuditEvent struct {
+	Type      string            `json:"type"`
+	ClientID  *string           `json:"client_id"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent"`
+	Details   map[string]string `json:"details"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func (m *ExportAuditEvent) Model(d *domain.AuditEvent) {
+	m.Type = string(d.Type)
+	m.ClientID = d.ClientID
+	m.IP = d.IP
+	m.UserAgent = d.UserAgent
+	m.Details = d.Details
+	m.CreatedAt = d.CreatedAt
+}
+
+// ExportMessage leaves the text out, it holds codes and links.
+type ExportMessage struct {
+	Kind      string     `json:"kind"`
+	Recipient string     `json:"recipient"`
+	Status    string     `json:"status"`
+	Provider  *string    `json:"provider"`
+	SentAt    *time.Time `json:"sent_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (m *ExportMessage) Model(d *domain.OutboundMessage) {
+	m.Kind = string(d.Kind)
+	m.Recipient = d.Recipient
+	m.Status = string(d.Status)
+	m.Provider = d.Provider
+	m.SentAt = d.SentAt
+	m.CreatedAt = d.CreatedAt
+}
@@ -0,0 +1,36 @@
+package viewmodels
+
+import "trainee-assignment-backend/internal/domain"
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+func (m *JSONWebKeySet) Model(d []*domain.JSONWebKey) {
+	m.Keys = make([]JSONWebKey, 0, len(d))
+	for _, k := range d {
+		m.Keys = append(m.Keys, JSONWebKey{
+			KeyType:   k.KeyType,
+			KeyID:     k.KeyID,
+			Use:       k.Use,
+			Algorithm: k.Algorithm,
+			N:         k.N,
+			E:         k.E,
+			Curve:     k.Curve,
+			X:         k.X,
+			Y:         k.Y,
+		})
+	}
+}
@@ -3,7 +3,7 @@ package viewmodels
 import (
 	"database/sql"
 	"encoding/json"
-	"regexp"
+	"errors"
 	"trainee-assignment-backend/internal/domain"
 
 	"github.com/go-ozzo/ozzo-validation/v3"
@@ -30,6 +30,7 @@ func (lr LoginRequest) Validate() error {
 			&lr,
 			validation.Field(&lr.Type, validation.Required),
 			validation.Field(&lr.RequestID, validation.Required, is.UUIDv4),
+			validation.Field(&lr.Payload, validation.By(validateLoginResend)),
 		)
 	case "confirm":
 		return validation.ValidateStruct(
@@ -38,18 +39,27 @@ func (lr LoginRequest) Validate() error {
 			validation.Field(&lr.RequestID, validation.Required, is.UUIDv4),
 			validation.Field(&lr.Payload, validation.By(validateLoginConfirm)),
 		)
+	case "totp":
+		return validation.ValidateStruct(
+			&lr,
+			validation.Field(&lr.Type, validation.Required),
+			validation.Field(&lr.RequestID, validation.Required, is.UUIDv4),
+			validation.Field(&lr.Payload, validation.By(validateLoginTOTP)),
+		)
 	default:
 		return sql.ErrNoRows
 	}
 }
 
 type LoginRequestStartPayload struct {
-	Phone string `json:"phone"`
+	Phone   string `json:"phone"`
+	Channel string `json:"channel"`
 }
 
 func (p LoginRequestStartPayload) Domain() *domain.LoginRequestStartPayload {
 	return &domain.LoginRequestStartPayload{
-		Phone: p.Phone,
+		Phone:   normalizePhone(p.Phone),
+		Channel: domain.OTPChannel(p.Channel),
 	}
 }
 
@@ -61,11 +71,35 @@ func validateLoginStart(value interface{}) error {
 
 	return validation.ValidateStruct(
 		&p,
-		validation.Field(
-			&p.Phone,
-			validation.Required,
-			validation.Match(regexp.MustCompile(`9\d{9}`)),
-		),
+		validation.Field(&p.Phone, validation.Required, phoneRule),
+		validation.Field(&p.Channel, otpChannelRule),
+	)
+}
+
+type LoginRequestResendPayload struct {
+	Channel string `json:"channel"`
+}
+
+func (p LoginRequestResendPayload) Domain() *domain.LoginRequestResendPayload {
+	return &domain.LoginRequestResendPayload{
+		Channel: domain.OTPChannel(p.Channel),
+	}
+}
+
+// Payload of a resend request is optional
+func validateLoginResend(value interface{}) error {
+	if len(value.(json.RawMessage)) == 0 {
+		return nil
+	}
+
+	var p LoginRequestResendPayload
+	if err := json.Unmarshal(value.(json.RawMessage), &p); err != nil {
+		return err
+	}
+
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(&p.Channel, otpChannelRule),
 	)
 }
 
@@ -94,6 +128,37 @@ func validateLoginConfirm(value interface{}) error {
 	)
 }
 
+type LoginRequestTOTPPayload struct {
+	TOTPCode     string `json:"totp_code"`
+	RecoveryCode string `json:"recovery_code"`
+	Fingerprint  string `json:"fingerprint"`
+}
+
+func (p LoginRequestTOTPPayload) Domain() *domain.LoginRequestTOTPPayload {
+	return &domain.LoginRequestTOTPPayload{
+		TOTPCode:     p.TOTPCode,
+		RecoveryCode: p.RecoveryCode,
+		Fingerprint:  p.Fingerprint,
+	}
+}
+
+func validateLoginTOTP(value interface{}) error {
+	var p LoginRequestTOTPPayload
+	if err := json.Unmarshal(value.(json.RawMessage), &p); err != nil {
+		return err
+	}
+
+	if p.TOTPCode == "" && p.RecoveryCode == "" {
+		return errors.New("either totp_code or recovery_code is required")
+	}
+
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(&p.TOTPCode, is.Digit, validation.Length(6, 6)),
+		validation.Field(&p.Fingerprint, validation.Required),
+	)
+}
+
 // Use only after validation
 func (lr *LoginRequest) Domain() *domain.LoginRequest {
 	d := &domain.LoginRequest{
@@ -110,10 +175,18 @@ func (lr *LoginRequest) Domain() *domain.LoginRequest {
 		var p LoginRequestStartPayload
 		_ = json.Unmarshal(lr.Payload, &p)
 		d.Payload = p.Domain()
+	case "resend":
+		var p LoginRequestResendPayload
+		_ = json.Unmarshal(lr.Payload, &p)
+		d.Payload = p.Domain()
 	case "confirm":
 		var p LoginRequestConfirmPayload
 		_ = json.Unmarshal(lr.Payload, &p)
 		d.Payload = p.Domain()
+	case "totp":
+		var p LoginRequestTOTPPayload
+		_ = json.Unmarshal(lr.Payload, &p)
+		d.Payload = p.Domain()
 	}
 
 	return d
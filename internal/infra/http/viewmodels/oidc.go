@@ -0,0 +1,192 @@
+package viewmodels
+
+import (
+	"strconv"
+	"strings"
+	"trainee-assignment-backend/internal/domain"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"github.com/go-ozzo/ozzo-validation/v3/is"
+)
+
+type OIDCConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func (m *OIDCConfiguration) Model(issuer string, keys []*domain.JSONWebKey) {
+	issuer = strings.TrimRight(issuer, "/")
+
+	algorithms := make([]string, 0, 1)
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			algorithms = append(algorithms, k.Algorithm)
+		}
+	}
+
+	*m = OIDCConfiguration{
+		Issuer:                issuer,
+		AuthorizationEndpoint: issuer + "/api/v1/oidc/authorize",
+		TokenEndpoint:         issuer + "/api/v1/oidc/token",
+		UserInfoEndpoint:      issuer + "/api/v1/oidc/userinfo",
+		JWKSURI:               issuer + "/.well-known/jwks.json",
+		ScopesSupported: []string{
+			domain.OIDCScopeOpenID,
+			domain.OIDCScopeProfile,
+			domain.OIDCScopePhone,
+			domain.OIDCScopeEmail,
+		},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "nonce",
+			"given_name", "middle_name", "family_name", "birthdate", "picture",
+			"phone_number", "phone_number_verified", "email", "email_verified",
+		},
+	}
+}
+
+type OIDCAuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Validate checks the parameters needed to return errors to the redirect URI,
+// the rest is checked by the service.
+func (r OIDCAuthorizationRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.ClientID, validation.Required),
+		validation.Field(&r.RedirectURI, validation.Required, is.URL),
+	)
+}
+
+func (r OIDCAuthorizationRequest) Domain() *domain.OIDCAuthorizationRequest {
+	return &domain.OIDCAuthorizationRequest{
+		ResponseType:        r.ResponseType,
+		ClientID:            r.ClientID,
+		RedirectURI:         r.RedirectURI,
+		Scopes:              strings.Fields(r.Scope),
+		State:               r.State,
+		Nonce:               r.Nonce,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+	}
+}
+
+type OIDCTokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+func (r OIDCTokenRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.GrantType, validation.Required),
+		validation.Field(&r.Code, validation.Required),
+		validation.Field(&r.RedirectURI, validation.Required),
+		validation.Field(&r.ClientID, validation.Required),
+		validation.Field(&r.CodeVerifier, validation.Required),
+	)
+}
+
+func (r OIDCTokenRequest) Domain() *domain.OIDCTokenRequest {
+	return &domain.OIDCTokenRequest{
+		GrantType:    r.GrantType,
+		Code:         r.Code,
+		RedirectURI:  r.RedirectURI,
+		ClientID:     r.ClientID,
+		ClientSecret: r.ClientSecret,
+		CodeVerifier: r.CodeVerifier,
+	}
+}
+
+type OIDCTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+func (m *OIDCTokenResponse) Model(d *domain.OIDCTokenResponse) {
+	m.AccessToken = d.AccessToken
+	m.TokenType = "Bearer"
+	m.ExpiresIn = int(d.ExpiresIn.Seconds())
+	m.IDToken = d.IDToken
+	m.Scope = strings.Join(d.Scopes, " ")
+}
+
+type OIDCUserInfo struct {
+	Subject             string  `json:"sub"`
+	GivenName           *string `json:"given_name,omitempty"`
+	MiddleName          *string `json:"middle_name,omitempty"`
+	FamilyName          *string `json:"family_name,omitempty"`
+	Birthdate           *string `json:"birthdate,omitempty"`
+	Picture             *string `json:"picture,omitempty"`
+	PhoneNumber         *string `json:"phone_number,omitempty"`
+	PhoneNumberVerified *bool   `json:"phone_number_verified,omitempty"`
+	Email               *string `json:"email,omitempty"`
+	EmailVerified       *bool   `json:"email_verified,omitempty"`
+}
+
+// Model fills the claims of the granted scopes.
+func (m *OIDCUserInfo) Model(d *domain.User, scopes []string) {
+	*m = OIDCUserInfo{
+		Subject: strconv.Itoa(d.ID),
+	}
+
+	for _, scope := range scopes {
+		switch scope {
+		case domain.OIDCScopeProfile:
+			m.GivenName = d.FirstName
+			m.MiddleName = d.MiddleName
+			m.FamilyName = d.LastName
+			if d.Birthday != nil {
+				birthdate := d.Birthday.Format("2006-01-02")
+				m.Birthdate = &birthdate
+			}
+			if picture, ok := d.AvatarURLs["large"]; ok {
+				m.Picture = &picture
+			}
+		case domain.OIDCScopePhone:
+			phone := d.Phone
+			verified := d.Status.IsConfirmed()
+			m.PhoneNumber = &phone
+			m.PhoneNumberVerified = &verified
+		case domain.OIDCScopeEmail:
+			if d.Email != nil {
+				verified := d.Status.IsEmailConfirmed()
+				m.Email = d.Email
+				m.EmailVerified = &verified
+			}
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package viewmodels
 
 import (
+	"errors"
 	validation "github.com/go-ozzo/ozzo-validation/v3"
 	"github.com/google/uuid"
 	"trainee-assignment-backend/internal/domain"
+	"trainee-assignment-backend/pkg/phone"
 )
 
 type AuthResponse struct {
@@ -24,6 +26,45 @@ func (ar *AuthResponse) Model(d *domain.AuthResponse) {
 	}
 }
 
+var otpChannelRule = validation.In(
+	string(domain.OTPChannelSMS),
+	string(domain.OTPChannelVoice),
+	string(domain.OTPChannelEmail),
+	string(domain.OTPChannelMessenger),
+)
+
+// National numbers are Russian, others are entered with a country code
+const defaultPhoneRegion = "RU"
+
+// phoneRule accepts numbers of any format able to receive an SMS, empty ones are left to validation.Required.
+var phoneRule = validation.By(func(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+
+	n, err := phone.Parse(s, defaultPhoneRegion)
+	if err != nil {
+		return err
+	}
+
+	if !n.CanReceiveSMS() {
+		return errors.New("phone number can't receive an SMS")
+	}
+
+	return nil
+})
+
+// normalizePhone returns the E.164 form of a valid number.
+func normalizePhone(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	normalized, _ := phone.Normalize(s, defaultPhoneRegion)
+	return normalized
+}
+
 type RefreshRequest struct {
 	Fingerprint string `json:"fingerprint"`
 }
@@ -36,7 +77,8 @@ func (rr RefreshRequest) Validate() error {
 }
 
 type JWTRequest struct {
-	UserID int    `json:"user_id"`
+	UserID      int    `json:"user_id"`
+	Fingerprint string `json:"fingerprint"`
 }
 
 func (jr JWTRequest) Validate() error {
@@ -48,8 +90,9 @@ func (jr JWTRequest) Validate() error {
 
 func (jr JWTRequest) Domain(userAgent, ip string) *domain.JWTRequest {
 	return &domain.JWTRequest{
-		UserID:    jr.UserID,
-		UserAgent: userAgent,
-		IP:        ip,
+		UserID:      jr.UserID,
+		UserAgent:   userAgent,
+		IP:          ip,
+		Fingerprint: jr.Fingerprint,
 	}
 }
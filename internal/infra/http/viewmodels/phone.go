@@ -0,0 +1,122 @@
+package viewmodels
+
+import (
+	"database/sql"
+	"encoding/json"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/go-ozzo/ozzo-validation/v3"
+	"github.com/go-ozzo/ozzo-validation/v3/is"
+	"github.com/google/uuid"
+)
+
+type PhoneChangeRequest struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+func (pr PhoneChangeRequest) Validate() error {
+	switch pr.Type {
+	case "start":
+		return validation.ValidateStruct(
+			&pr,
+			validation.Field(&pr.Type, validation.Required),
+			validation.Field(&pr.Payload, validation.By(validatePhoneChangeStart)),
+		)
+	case "resend":
+		return validation.ValidateStruct(
+			&pr,
+			validation.Field(&pr.Type, validation.Required),
+			validation.Field(&pr.RequestID, validation.Required, is.UUIDv4),
+			validation.Field(&pr.Payload, validation.By(validateRegistrationResend)),
+		)
+	case "confirm-old", "confirm-new":
+		return validation.ValidateStruct(
+			&pr,
+			validation.Field(&pr.Type, validation.Required),
+			validation.Field(&pr.RequestID, validation.Required, is.UUIDv4),
+			validation.Field(&pr.Payload, validation.By(validatePhoneChangeConfirm)),
+		)
+	default:
+		return sql.ErrNoRows
+	}
+}
+
+type PhoneChangeRequestStartPayload struct {
+	Phone   string `json:"phone"`
+	Channel string `json:"channel"`
+}
+
+func (p PhoneChangeRequestStartPayload) Domain() *domain.PhoneChangeRequestStartPayload {
+	return &domain.PhoneChangeRequestStartPayload{
+		Phone:   normalizePhone(p.Phone),
+		Channel: domain.OTPChannel(p.Channel),
+	}
+}
+
+func validatePhoneChangeStart(value interface{}) error {
+	var p PhoneChangeRequestStartPayload
+	if err := json.Unmarshal(value.(json.RawMessage), &p); err != nil {
+		return err
+	}
+
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(&p.Phone, validation.Required, phoneRule),
+		validation.Field(&p.Channel, otpChannelRule),
+	)
+}
+
+type PhoneChangeRequestConfirmPayload struct {
+	Code string `json:"code"`
+}
+
+func (p PhoneChangeRequestConfirmPayload) Domain() *domain.PhoneChangeRequestConfirmPayload {
+	return &domain.PhoneChangeRequestConfirmPayload{
+		Code: p.Code,
+	}
+}
+
+func validatePhoneChangeConfirm(value interface{}) error {
+	var p PhoneChangeRequestConfirmPayload
+	if err := json.Unmarshal(value.(json.RawMessage), &p); err != nil {
+		return err
+	}
+
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(&p.Code, validation.Required),
+	)
+}
+
+// Use only after validation
+func (pr *PhoneChangeRequest) Domain() *domain.PhoneChangeRequest {
+	d := &domain.PhoneChangeRequest{
+		Type: domain.PhoneChangeRequestType(pr.Type),
+	}
+
+	requestID, err := uuid.Parse(pr.RequestID)
+	if err == nil {
+		d.RequestID = requestID
+	}
+
+	switch pr.Type {
+	case "start":
+		var p PhoneChangeRequestStartPayload
+		_ = json.Unmarshal(pr.Payload, &p)
+		d.Payload = p.Domain()
+	case "resend":
+		var p RegistrationRequestResendPayload
+		_ = json.Unmarshal(pr.Payload, &p)
+		d.Payload = &domain.PhoneChangeRequestResendPayload{
+			Channel: domain.OTPChannel(p.Channel),
+		}
+	case "confirm-old", "confirm-new":
+		var p PhoneChangeRequestConfirmPayload
+		_ = json.Unmarshal(pr.Payload, &p)
+		d.Payload = p.Domain()
+	}
+
+	return d
+}
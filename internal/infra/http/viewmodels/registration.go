@@ -3,7 +3,6 @@ package viewmodels
 import (
 	"database/sql"
 	"encoding/json"
-	"regexp"
 	"strings"
 	"trainee-assignment-backend/internal/domain"
 
@@ -31,6 +30,7 @@ func (rr RegistrationRequest) Validate() error {
 			&rr,
 			validation.Field(&rr.Type, validation.Required),
 			validation.Field(&rr.RequestID, validation.Required, is.UUIDv4),
+			validation.Field(&rr.Payload, validation.By(validateRegistrationResend)),
 		)
 	case "confirm":
 		return validation.ValidateStruct(
@@ -52,12 +52,14 @@ func (rr RegistrationRequest) Validate() error {
 }
 
 type RegistrationRequestStartPayload struct {
-	Phone string `json:"phone"`
+	Phone   string `json:"phone"`
+	Channel string `json:"channel"`
 }
 
 func (p RegistrationRequestStartPayload) Domain() *domain.RegistrationRequestStartPayload {
 	return &domain.RegistrationRequestStartPayload{
-		Phone: p.Phone,
+		Phone:   normalizePhone(p.Phone),
+		Channel: domain.OTPChannel(p.Channel),
 	}
 }
 
@@ -69,11 +71,35 @@ func validateRegistrationStart(value interface{}) error {
 
 	return validation.ValidateStruct(
 		&p,
-		validation.Field(
-			&p.Phone,
-			validation.Required,
-			validation.Match(regexp.MustCompile(`9\d{9}`)),
-		),
+		validation.Field(&p.Phone, validation.Required, phoneRule),
+		validation.Field(&p.Channel, otpChannelRule),
+	)
+}
+
+type RegistrationRequestResendPayload struct {
+	Channel string `json:"channel"`
+}
+
+func (p RegistrationRequestResendPayload) Domain() *domain.RegistrationRequestResendPayload {
+	return &domain.RegistrationRequestResendPayload{
+		Channel: domain.OTPChannel(p.Channel),
+	}
+}
+
+// Payload of a resend request is optional
+func validateRegistrationResend(value interface{}) error {
+	if len(value.(json.RawMessage)) == 0 {
+		return nil
+	}
+
+	var p RegistrationRequestResendPayload
+	if err := json.Unmarshal(value.(json.RawMessage), &p); err != nil {
+		return err
+	}
+
+	return validation.ValidateStruct(
+		&p,
+		validation.Field(&p.Channel, otpChannelRule),
 	)
 }
 
@@ -153,6 +179,10 @@ func (rr *RegistrationRequest) Domain() *domain.RegistrationRequest {
 		var p RegistrationRequestStartPayload
 		_ = json.Unmarshal(rr.Payload, &p)
 		d.Payload = p.Domain()
+	case "resend":
+		var p RegistrationRequestResendPayload
+		_ = json.Unmarshal(rr.Payload, &p)
+		d.Payload = p.Domain()
 	case "confirm":
 		var p RegistrationRequestConfirmPayload
 		_ = json.Unmarshal(rr.Payload, &p)
@@ -0,0 +1,51 @@
+package viewmodels
+
+import (
+	"time"
+	"trainee-assignment-backend/internal/domain"
+	"trainee-assignment-backend/pkg/useragent"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+)
+
+type Session struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	ClientType     string    `json:"client_type"`
+	Device         string    `json:"device"`
+	OS             string    `json:"os"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	IP             string    `json:"ip"`
+	Current        bool      `json:"current"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+func (m *Session) Model(d *domain.RefreshSession) {
+	ua := useragent.Parse(d.UserAgent)
+
+	m.ID = d.ID
+	m.Name = ua.String()
+	m.ClientType = string(d.ClientType)
+	m.Device = ua.Device
+	m.OS = ua.OS
+	m.Browser = ua.Browser
+	m.BrowserVersion = ua.BrowserVersion
+	m.IP = d.IP
+	m.Current = d.Current
+	m.CreatedAt = d.CreatedAt
+	m.ExpiresAt = d.ExpiresAt
+}
+
+// SessionsRevocationRequest carries the token of a "this wasn't me" link.
+type SessionsRevocationRequest struct {
+	Token string `json:"token"`
+}
+
+func (r SessionsRevocationRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.Token, validation.Required),
+	)
+}
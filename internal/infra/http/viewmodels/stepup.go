@@ -0,0 +1,58 @@
+package viewmodels
+
+import (
+	"trainee-assignment-backend/internal/domain"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"github.com/go-ozzo/ozzo-validation/v3/is"
+	"github.com/google/uuid"
+)
+
+type StepUpRequest struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+	Channel   string `json:"channel"`
+	Code      string `json:"code"`
+}
+
+func (r StepUpRequest) Validate() error {
+	switch r.Type {
+	case string(domain.StepUpRequestTypeStart):
+		return validation.ValidateStruct(
+			&r,
+			validation.Field(&r.Channel, otpChannelRule),
+		)
+	case string(domain.StepUpRequestTypeConfirm):
+		return validation.ValidateStruct(
+			&r,
+			validation.Field(&r.RequestID, validation.Required, is.UUIDv4),
+			validation.Field(&r.Code, validation.Required),
+		)
+	case string(domain.StepUpRequestTypeTOTP):
+		return validation.ValidateStruct(
+			&r,
+			validation.Field(&r.Code, validation.Required, is.Digit, validation.Length(6, 6)),
+		)
+	default:
+		return validation.ValidateStruct(
+			&r,
+			validation.Field(&r.Type, validation.Required, validation.In("start", "confirm", "totp")),
+		)
+	}
+}
+
+// Use only after validation
+func (r *StepUpRequest) Domain() *domain.StepUpRequest {
+	d := &domain.StepUpRequest{
+		Type:    domain.StepUpRequestType(r.Type),
+		Channel: domain.OTPChannel(r.Channel),
+		Code:    r.Code,
+	}
+
+	requestID, err := uuid.Parse(r.RequestID)
+	if err == nil {
+		d.RequestID = requestID
+	}
+
+	return d
+}
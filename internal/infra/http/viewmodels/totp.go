@@ -0,0 +1,32 @@
+package viewmodels
+
+import (
+	"trainee-assignment-backend/internal/domain"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+)
+
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+func (m *TOTPEnrollment) Model(d *domain.TOTPEnrollment) {
+	m.Secret = d.Secret
+	m.ProvisioningURI = d.ProvisioningURI
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (r TOTPCodeRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.Code, validation.Required),
+	)
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -1,6 +1,10 @@
 package viewmodels
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 	"trainee-assignment-backend/internal/domain"
 
@@ -9,15 +13,16 @@ import (
 )
 
 type User struct {
-	ID         int        `json:"id"`
-	Phone      string     `json:"phone"`
-	FirstName  string     `json:"first_name"`
-	MiddleName string     `json:"middle_name"`
-	LastName   string     `json:"last_name"`
-	Birthday   string     `json:"birthday"`
-	City       string     `json:"city"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  *time.Time `json:"updated_at"`
+	ID         int               `json:"id"`
+	Phone      string            `json:"phone"`
+	FirstName  string            `json:"first_name"`
+	MiddleName string            `json:"middle_name"`
+	LastName   string            `json:"last_name"`
+	Birthday   string            `json:"birthday"`
+	City       string            `json:"city"`
+	Avatar     map[string]string `json:"avatar"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  *time.Time        `json:"updated_at"`
 }
 
 func (m *User) Model(d *domain.User) {
@@ -38,37 +43,110 @@ func (m *User) Model(d *domain.User) {
 	if d.City != nil {
 		m.City = *d.City
 	}
+	m.Avatar = d.AvatarURLs
 	m.CreatedAt = d.CreatedAt
 	m.UpdatedAt = d.UpdatedAt
 }
 
+// ETag of the profile is its version, If-Match takes it back.
+func ETag(d *domain.User) string {
+	return `"` + strconv.FormatInt(d.Version(), 36) + `"`
+}
+
+// ParseETag returns false for weak and unknown tags, they never match.
+func ParseETag(etag string) (int64, bool) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(etag[1:len(etag)-1], 36, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// ProfileUpdateRequest is a JSON merge patch of the profile (RFC 7396), null clears a nullable field.
 type ProfileUpdateRequest struct {
-	FirstName  string `json:"first_name"`
-	MiddleName string `json:"middle_name"`
-	LastName   string `json:"last_name"`
-	Birthday   string `json:"birthday"`
-	City       string `json:"city"`
+	fields map[string]*string
+}
+
+// profileFields are rules of the fields a patch may have, names must be set.
+var profileFields = map[string]struct {
+	field    domain.ProfileField
+	nullable bool
+	rules    []validation.Rule
+}{
+	"first_name":  {domain.ProfileFieldFirstName, false, []validation.Rule{validation.Required}},
+	"middle_name": {domain.ProfileFieldMiddleName, true, []validation.Rule{validation.Required}},
+	"last_name":   {domain.ProfileFieldLastName, false, []validation.Rule{validation.Required}},
+	"birthday":    {domain.ProfileFieldBirthday, true, []validation.Rule{validation.Required, validation.Date("2006-01-02")}},
+	"city":        {domain.ProfileFieldCity, true, []validation.Rule{validation.Required}},
+}
+
+func (r *ProfileUpdateRequest) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	r.fields = make(map[string]*string, len(raw))
+	for name, value := range raw {
+		if string(value) == "null" {
+			r.fields[name] = nil
+			continue
+		}
+
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		r.fields[name] = &s
+	}
+
+	return nil
 }
 
-func (r *ProfileUpdateRequest) Domain() *domain.ProfileUpdateRequest {
-	return &domain.ProfileUpdateRequest{
-		FirstName:  r.FirstName,
-		MiddleName: r.MiddleName,
-		LastName:   r.LastName,
-		Birthday:   r.Birthday,
-		City:       r.City,
+// Domain takes the If-Match header, it's empty or * when the version isn't checked.
+func (r *ProfileUpdateRequest) Domain(ifMatch string) *domain.ProfileUpdateRequest {
+	d := &domain.ProfileUpdateRequest{
+		Fields: make(map[domain.ProfileField]*string, len(r.fields)),
+	}
+	for name, value := range r.fields {
+		d.Fields[profileFields[name].field] = value
 	}
+
+	if ifMatch != "" && strings.TrimSpace(ifMatch) != "*" {
+		// A tag that can't be parsed fails the check
+		version, _ := ParseETag(ifMatch)
+		d.IfMatch = &version
+	}
+
+	return d
 }
 
 func (r ProfileUpdateRequest) Validate() error {
-	return validation.ValidateStruct(
-		&r,
-		validation.Field(&r.FirstName, validation.Required),
-		validation.Field(&r.MiddleName, validation.Required),
-		validation.Field(&r.LastName, validation.Required),
-		validation.Field(&r.Birthday, validation.Required, validation.Date("2006-01-02")),
-		validation.Field(&r.City, validation.Required),
-	)
+	for name, value := range r.fields {
+		f, ok := profileFields[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown field", name)
+		}
+
+		if value == nil {
+			if !f.nullable {
+				return fmt.Errorf("%s: cannot be null", name)
+			}
+			continue
+		}
+
+		if err := validation.Validate(*value, f.rules...); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
 }
 
 type EmailChangeRequest struct {
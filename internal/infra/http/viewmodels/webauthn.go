@@ -0,0 +1,290 @@
+package viewmodels
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"trainee-assignment-backend/internal/domain"
+
+	validation "github.com/go-ozzo/ozzo-validation/v3"
+	"github.com/go-ozzo/ozzo-validation/v3/is"
+	"github.com/google/uuid"
+)
+
+// WebAuthn public key credential algorithms (COSE): ES256, EdDSA, RS256.
+var webAuthnAlgorithms = []int{-7, -8, -257}
+
+// Base64URL is a binary value of WebAuthn JSON, padding is optional.
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if s == "" {
+		*b = nil
+		return nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+	return nil
+}
+
+type webAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type webAuthnUser struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type webAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type webAuthnCredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+type webAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type webAuthnCreationOptions struct {
+	Challenge              Base64URL                      `json:"challenge"`
+	RP                     webAuthnRelyingParty           `json:"rp"`
+	User                   webAuthnUser                   `json:"user"`
+	PubKeyCredParams       []webAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	ExcludeCredentials     []webAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection webAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                         `json:"attestation"`
+}
+
+type webAuthnRequestOptions struct {
+	Challenge        Base64URL                      `json:"challenge"`
+	RPID             string                         `json:"rpId"`
+	Timeout          int64                          `json:"timeout"`
+	AllowCredentials []webAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+func webAuthnUserVerification(required bool) string {
+	if required {
+		return "required"
+	}
+
+	return "preferred"
+}
+
+func webAuthnDescriptors(ids [][]byte) []webAuthnCredentialDescriptor {
+	descriptors := make([]webAuthnCredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		descriptors = append(descriptors, webAuthnCredentialDescriptor{
+			Type: "public-key",
+			ID:   id,
+		})
+	}
+
+	return descriptors
+}
+
+// WebAuthnRegistrationOptions holds options for navigator.credentials.create().
+type WebAuthnRegistrationOptions struct {
+	RequestID string                  `json:"request_id"`
+	PublicKey webAuthnCreationOptions `json:"public_key"`
+}
+
+func (m *WebAuthnRegistrationOptions) Model(requestID uuid.UUID, d *domain.WebAuthnRegistrationOptions) {
+	m.RequestID = requestID.String()
+
+	params := make([]webAuthnCredentialParameter, 0, len(webAuthnAlgorithms))
+	for _, alg := range webAuthnAlgorithms {
+		params = append(params, webAuthnCredentialParameter{
+			Type: "public-key",
+			Alg:  alg,
+		})
+	}
+
+	m.PublicKey = webAuthnCreationOptions{
+		Challenge: d.Challenge,
+		RP: webAuthnRelyingParty{
+			ID:   d.RPID,
+			Name: d.RPName,
+		},
+		User: webAuthnUser{
+			ID:          d.UserHandle,
+			Name:        d.UserName,
+			DisplayName: d.UserDisplayName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            d.Timeout.Milliseconds(),
+		ExcludeCredentials: webAuthnDescriptors(d.ExcludeCredentialIDs),
+		AuthenticatorSelection: webAuthnAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: webAuthnUserVerification(d.UserVerification),
+		},
+		Attestation: "none",
+	}
+}
+
+// WebAuthnLoginOptions holds options for navigator.credentials.get().
+type WebAuthnLoginOptions struct {
+	RequestID string                 `json:"request_id"`
+	PublicKey webAuthnRequestOptions `json:"public_key"`
+}
+
+func (m *WebAuthnLoginOptions) Model(requestID uuid.UUID, d *domain.WebAuthnLoginOptions) {
+	m.RequestID = requestID.String()
+	m.PublicKey = webAuthnRequestOptions{
+		Challenge:        d.Challenge,
+		RPID:             d.RPID,
+		Timeout:          d.Timeout.Milliseconds(),
+		AllowCredentials: webAuthnDescriptors(d.AllowCredentialIDs),
+		UserVerification: webAuthnUserVerification(d.UserVerification),
+	}
+}
+
+type WebAuthnAttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AttestationObject Base64URL `json:"attestationObject"`
+}
+
+func (r WebAuthnAttestationResponse) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.ClientDataJSON, validation.Required),
+		validation.Field(&r.AttestationObject, validation.Required),
+	)
+}
+
+type WebAuthnAttestationCredential struct {
+	RawID    Base64URL                   `json:"rawId"`
+	Type     string                      `json:"type"`
+	Response WebAuthnAttestationResponse `json:"response"`
+}
+
+func (c WebAuthnAttestationCredential) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.RawID, validation.Required),
+		validation.Field(&c.Type, validation.Required, validation.In("public-key")),
+		validation.Field(&c.Response),
+	)
+}
+
+type WebAuthnRegistrationFinishRequest struct {
+	RequestID  string                        `json:"request_id"`
+	Credential WebAuthnAttestationCredential `json:"credential"`
+}
+
+func (r WebAuthnRegistrationFinishRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.RequestID, validation.Required, is.UUIDv4),
+		validation.Field(&r.Credential),
+	)
+}
+
+func (r WebAuthnRegistrationFinishRequest) Domain() (uuid.UUID, *domain.WebAuthnAttestation) {
+	return uuid.MustParse(r.RequestID), &domain.WebAuthnAttestation{
+		CredentialID:      r.Credential.RawID,
+		ClientDataJSON:    r.Credential.Response.ClientDataJSON,
+		AttestationObject: r.Credential.Response.AttestationObject,
+	}
+}
+
+type WebAuthnLoginBeginRequest struct {
+	Phone string `json:"phone"`
+}
+
+func (r WebAuthnLoginBeginRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.Phone, phoneRule),
+	)
+}
+
+// Domain is the normalized phone, empty for discoverable credentials.
+func (r WebAuthnLoginBeginRequest) Domain() string {
+	return normalizePhone(r.Phone)
+}
+
+type WebAuthnAssertionResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AuthenticatorData Base64URL `json:"authenticatorData"`
+	Signature         Base64URL `json:"signature"`
+	UserHandle        Base64URL `json:"userHandle"`
+}
+
+func (r WebAuthnAssertionResponse) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.ClientDataJSON, validation.Required),
+		validation.Field(&r.AuthenticatorData, validation.Required),
+		validation.Field(&r.Signature, validation.Required),
+	)
+}
+
+type WebAuthnAssertionCredential struct {
+	RawID    Base64URL                 `json:"rawId"`
+	Type     string                    `json:"type"`
+	Response WebAuthnAssertionResponse `json:"response"`
+}
+
+func (c WebAuthnAssertionCredential) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.RawID, validation.Required),
+		validation.Field(&c.Type, validation.Required, validation.In("public-key")),
+		validation.Field(&c.Response),
+	)
+}
+
+type WebAuthnLoginFinishRequest struct {
+	RequestID   string                      `json:"request_id"`
+	Fingerprint string                      `json:"fingerprint"`
+	Credential  WebAuthnAssertionCredential `json:"credential"`
+}
+
+func (r WebAuthnLoginFinishRequest) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.RequestID, validation.Required, is.UUIDv4),
+		validation.Field(&r.Fingerprint, validation.Required),
+		validation.Field(&r.Credential),
+	)
+}
+
+func (r WebAuthnLoginFinishRequest) Domain(userAgent, ip string) *domain.WebAuthnLoginRequest {
+	return &domain.WebAuthnLoginRequest{
+		RequestID: uuid.MustParse(r.RequestID),
+		Assertion: &domain.WebAuthnAssertion{
+			CredentialID:      r.Credential.RawID,
+			ClientDataJSON:    r.Credential.Response.ClientDataJSON,
+			AuthenticatorData: r.Credential.Response.AuthenticatorData,
+			Signature:         r.Credential.Response.Signature,
+			UserHandle:        r.Credential.Response.UserHandle,
+		},
+		Fingerprint: r.Fingerprint,
+		UserAgent:   userAgent,
+		IP:          ip,
+	}
+}
@@ -0,0 +1,9 @@
+package messenger
+
+import "trainee-assignment-backend/pkg/httpapi"
+
+type Config struct {
+	Driver string `long:"driver" env:"DRIVER" description:"Messenger bot driver (log, http)" default:"log"`
+
+	HTTP *httpapi.Config `group:"Messenger HTTP driver args" namespace:"http" env-namespace:"HTTP"`
+}
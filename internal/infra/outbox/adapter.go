@@ -0,0 +1,236 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+type adapter struct {
+	logger    *logrus.Logger
+	config    *Config
+	db        domain.Database
+	email     domain.Email
+	sms       domain.SMSSender
+	voice     domain.VoiceCaller
+	messenger domain.Messenger
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	abort     chan struct{}
+	abortOnce sync.Once
+	done      chan struct{}
+}
+
+// Creating a new pool of workers sending messages from the outbox.
+func NewAdapter(
+	logger *logrus.Logger,
+	config *Config,
+	db domain.Database,
+	email domain.Email,
+	sms domain.SMSSender,
+	voice domain.VoiceCaller,
+	messenger domain.Messenger,
+) domain.Worker {
+	return &adapter{
+		logger:    logger,
+		config:    config,
+		db:        db,
+		email:     email,
+		sms:       sms,
+		voice:     voice,
+		messenger: messenger,
+		stop:      make(chan struct{}),
+		abort:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run workers until the outbox is drained after Shutdown.
+func (a *adapter) Run() error {
+	a.logger.WithField("workers", a.config.Workers).Info("Sending messages from the outbox.")
+
+	var wg sync.WaitGroup
+	for i := 0; i < a.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.work()
+		}()
+	}
+
+	wg.Wait()
+	close(a.done)
+
+	return nil
+}
+
+// Shutdown stops polling and waits for the due messages to be sent.
+// Messages left when the context is done are picked up after the restart.
+func (a *adapter) Shutdown(ctx context.Context) error {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		a.abortOnce.Do(func() {
+			close(a.abort)
+		})
+		a.logger.Error("Error draining the outbox, some messages are left!")
+		return ctx.Err()
+	}
+}
+
+func (a *adapter) work() {
+	failures := 0
+	for {
+		messages, err := a.db.ClaimOutboundMessages(a.config.BatchSize, a.config.Lease)
+		if err != nil {
+			// The database is unavailable, polling is backed off
+			failures++
+			delay := a.backoff(failures)
+			a.logger.WithError(err).WithField("retry_in", delay).Error("Error while claiming outbound messages!")
+
+			select {
+			case <-a.stop:
+				return
+			case <-time.After(delay):
+			}
+
+			continue
+		}
+		failures = 0
+
+		for _, m := range messages {
+			select {
+			case <-a.abort:
+				return
+			default:
+				a.process(m)
+			}
+		}
+
+		// Keep going while there is something to send
+		if len(messages) > 0 {
+			continue
+		}
+
+		select {
+		case <-a.stop:
+			return
+		case <-time.After(a.config.PollInterval):
+		}
+	}
+}
+
+func (a *adapter) process(m *domain.OutboundMessage) {
+	logger := a.logger.WithFields(logrus.Fields{
+		"message_id": m.ID,
+		"kind":       m.Kind,
+		"attempt":    m.Attempts,
+	})
+
+	// A message left unmarked is claimed again once the lease is over
+	if m.ExpiresAt != nil && time.Now().After(*m.ExpiresAt) {
+		logger.Warn("Outbound message is expired!")
+		if err := a.db.MarkOutboundMessageDead(m.ID, "expired"); err != nil {
+			logger.WithError(err).Error("Error while moving an expired outbound message to the dead letters!")
+		}
+		return
+	}
+
+	provider, providerMessageID, err := a.dispatch(m)
+	if err != nil {
+		if m.Attempts >= a.config.MaxAttempts {
+			logger.WithError(err).Error("Outbound message is moved to the dead letters!")
+			if err := a.db.MarkOutboundMessageDead(m.ID, err.Error()); err != nil {
+				logger.WithError(err).Error("Error while moving an outbound message to the dead letters!")
+			}
+			return
+		}
+
+		logger.WithError(err).Warn("Error while sending an outbound message, it will be retried!")
+		if err := a.db.MarkOutboundMessageFailed(m.ID, err.Error(), time.Now().In(time.UTC).Add(a.backoff(m.Attempts))); err != nil {
+			logger.WithError(err).Error("Error while marking an outbound message failed!")
+		}
+		return
+	}
+
+	logger.WithField("provider", provider).Info("Outbound message is sent.")
+	if err := a.db.MarkOutboundMessageSent(m.ID, provider, providerMessageID); err != nil {
+		// The message may be sent twice after the lease
+		logger.WithError(err).Error("Error while marking an outbound message sent!")
+	}
+}
+
+func (a *adapter) dispatch(m *domain.OutboundMessage) (string, string, error) {
+	switch m.Kind {
+	case domain.OutboundMessageKindSMS:
+		receipt, err := a.sms.SendSMS(m.Recipient, m.Payload["text"])
+		if err != nil {
+			return "", "", err
+		}
+
+		return receipt.Provider, receipt.MessageID, nil
+	case domain.OutboundMessageKindVoice:
+		receipt, err := a.voice.Call(m.Recipient, m.Payload["text"])
+		if err != nil {
+			return "", "", err
+		}
+
+		return receipt.Provider, receipt.MessageID, nil
+	case domain.OutboundMessageKindMessenger:
+		receipt, err := a.messenger.SendMessage(m.Recipient, m.Payload["text"])
+		if err != nil {
+			return "", "", err
+		}
+
+		return receipt.Provider, receipt.MessageID, nil
+	case domain.OutboundMessageKindEmailOTP:
+		if err := a.email.SendOTP(m.Recipient, m.Payload["name"], m.Payload["code"]); err != nil {
+			return "", "", err
+		}
+
+		return "smtp", "", nil
+	case domain.OutboundMessageKindEmailConfirmation:
+		if err := a.email.SendEmailConfirmation(m.Recipient, m.Payload["name"], m.Payload["token"]); err != nil {
+			return "", "", err
+		}
+
+		return "smtp", "", nil
+	case domain.OutboundMessageKindEmailNewDevice:
+		if err := a.email.SendNewDeviceLogin(
+			m.Recipient,
+			m.Payload["name"],
+			m.Payload["device"],
+			m.Payload["location"],
+			m.Payload["link"],
+		); err != nil {
+			return "", "", err
+		}
+
+		return "smtp", "", nil
+	default:
+		return "", "", fmt.Errorf("unknown message kind %q", m.Kind)
+	}
+}
+
+func (a *adapter) backoff(attempt int) time.Duration {
+	backoff := a.config.RetryBackoff
+	for i := 1; i < attempt && backoff < a.config.RetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > a.config.RetryMaxBackoff {
+		backoff = a.config.RetryMaxBackoff
+	}
+
+	return backoff
+}
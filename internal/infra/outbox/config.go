@@ -0,0 +1,13 @@
+package outbox
+
+import "time"
+
+type Config struct {
+	Workers         int           `long:"workers" env:"WORKERS" description:"Number of outbox workers" default:"4"`
+	BatchSize       int           `long:"batch-size" env:"BATCH_SIZE" description:"Messages claimed by a worker at once" default:"10"`
+	PollInterval    time.Duration `long:"poll-interval" env:"POLL_INTERVAL" description:"Delay between polls of an empty outbox" default:"1s"`
+	Lease           time.Duration `long:"lease" env:"LEASE" description:"Time a claimed message is locked for a worker" default:"1m"`
+	MaxAttempts     int           `long:"max-attempts" env:"MAX_ATTEMPTS" description:"Attempts before a message goes to the dead letters" default:"5"`
+	RetryBackoff    time.Duration `long:"retry-backoff" env:"RETRY_BACKOFF" description:"Initial backoff between attempts" default:"10s"`
+	RetryMaxBackoff time.Duration `long:"retry-max-backoff" env:"RETRY_MAX_BACKOFF" description:"Maximum backoff between attempts" default:"10m"`
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+type adapter struct {
+	policies     map[domain.ClientType]*domain.SessionPolicy
+	stepUpMaxAge time.Duration
+}
+
+func NewAdapter(config *Config) (domain.SessionPolicies, error) {
+	if config.StepUpMaxAge <= 0 {
+		return nil, fmt.Errorf("step-up max age must be positive")
+	}
+
+	a := &adapter{
+		policies:     make(map[domain.ClientType]*domain.SessionPolicy, len(domain.ClientTypes)),
+		stepUpMaxAge: config.StepUpMaxAge,
+	}
+
+	for clientType, c := range map[domain.ClientType]*ClientConfig{
+		domain.ClientTypeWeb:     config.Web,
+		domain.ClientTypeMobile:  config.Mobile,
+		domain.ClientTypeService: config.Service,
+	} {
+		if c.AccessTokenTTL <= 0 || c.RefreshTokenTTL <= c.AccessTokenTTL {
+			return nil, fmt.Errorf("%s refresh token must outlive a positive access token lifetime", clientType)
+		}
+
+		if c.MaxSessions < 1 {
+			return nil, fmt.Errorf("%s clients must be allowed at least one session", clientType)
+		}
+
+		a.policies[clientType] = &domain.SessionPolicy{
+			AccessTokenTTL:  c.AccessTokenTTL,
+			RefreshTokenTTL: c.RefreshTokenTTL,
+			Sliding:         c.Expiry == "sliding",
+			MaxSessions:     c.MaxSessions,
+		}
+	}
+
+	return a, nil
+}
+
+// Policy of an unknown client type is the web one.
+func (a *adapter) Policy(clientType domain.ClientType) *domain.SessionPolicy {
+	if p, ok := a.policies[clientType]; ok {
+		return p
+	}
+
+	return a.policies[domain.ClientTypeWeb]
+}
+
+func (a *adapter) StepUpMaxAge() time.Duration {
+	return a.stepUpMaxAge
+}
@@ -0,0 +1,19 @@
+package policy
+
+import "time"
+
+type Config struct {
+	Web     *ClientConfig `group:"Web client args" namespace:"web" env-namespace:"WEB"`
+	Mobile  *ClientConfig `group:"Mobile client args" namespace:"mobile" env-namespace:"MOBILE"`
+	Service *ClientConfig `group:"Service client args" namespace:"service" env-namespace:"SERVICE"`
+
+	StepUpMaxAge time.Duration `long:"step-up-max-age" env:"STEP_UP_MAX_AGE" description:"How recent an authentication must be for sensitive operations" default:"10m"`
+}
+
+// ClientConfig is a session policy of a client type.
+type ClientConfig struct {
+	AccessTokenTTL  time.Duration `long:"access-token-ttl" env:"ACCESS_TOKEN_TTL" description:"Access token lifetime" default:"30m"`
+	RefreshTokenTTL time.Duration `long:"refresh-token-ttl" env:"REFRESH_TOKEN_TTL" description:"Refresh token lifetime" default:"1440h"`
+	Expiry          string        `long:"expiry" env:"EXPIRY" description:"Sliding sessions are prolonged by a refresh, absolute ones end after the lifetime since the login" choice:"sliding" choice:"absolute" default:"sliding"`
+	MaxSessions     int           `long:"max-sessions" env:"MAX_SESSIONS" description:"Maximum concurrent sessions of a user" default:"5"`
+}
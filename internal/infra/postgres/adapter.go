@@ -2,7 +2,10 @@ package postgres
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 	"trainee-assignment-backend/internal/domain"
 	"trainee-assignment-backend/internal/infra/postgres/models"
@@ -159,12 +162,21 @@ func (a *adapter) GetUser(id int) (*domain.User, error) {
 				    birthday,
 				    city,
 				    email,
+				    avatar,
+				    status_reason,
+				    status_expires_at,
+				    ARRAY(SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				    	WHERE ur.user_id = users.id ORDER BY r.name) AS roles,
 				    created_at,
 				    updated_at
 				FROM users
 				WHERE id = $1`,
 		id,
 	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+
 		a.logger.WithError(err).Error("Error while trying to get a user!")
 		return nil, domain.ErrInternalDatabase
 	}
@@ -172,38 +184,66 @@ func (a *adapter) GetUser(id int) (*domain.User, error) {
 	return m.Domain(), nil
 }
 
+// UpdateUser applies the patch to the row locked for the version check, an empty patch only reads the user.
 func (a *adapter) UpdateUser(id int, r *domain.ProfileUpdateRequest) (*domain.User, error) {
-	var m models.User
-	if err := a.db.Get(
-		&m,
-		`UPDATE users
-				SET first_name  = $2,
-				    middle_name = $3,
-				    last_name   = $4,
-				    birthday    = $5,
-				    city        = $6
-				WHERE id = $1 RETURNING id,
-				       status,
-				       phone,
-				       first_name,
-				       middle_name,
-				       last_name,
-				       birthday,
-				       city,
-				       email,
-				       created_at,
-				       updated_at`,
-		id,
-		r.FirstName,
-		r.MiddleName,
-		r.LastName,
-		r.Birthday,
-		r.City,
-	); err != nil {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while starting a transaction!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	//noinspection ALL
+	defer tx.Rollback()
+
+	var version models.User
+	if err := tx.Get(&version, `SELECT created_at, updated_at FROM users WHERE id = $1 FOR UPDATE`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+
 		a.logger.WithError(err).Error("Error while trying to get a user!")
 		return nil, domain.ErrInternalDatabase
 	}
 
+	if r.IfMatch != nil && version.Domain().Version() != *r.IfMatch {
+		return nil, domain.ErrProfileVersionMismatch
+	}
+
+	// Columns come from the fixed list of fields only
+	args := []interface{}{id}
+	sets := make([]string, 0, len(r.Fields))
+	for _, field := range domain.ProfileFields {
+		value, ok := r.Fields[field]
+		if !ok {
+			continue
+		}
+
+		if value == nil {
+			args = append(args, nil)
+		} else {
+			args = append(args, *value)
+		}
+		sets = append(sets, fmt.Sprintf("%s = $%d", field, len(args)))
+	}
+
+	columns := `id, status, phone, first_name, middle_name, last_name, birthday, city, email, avatar,
+				    created_at, updated_at`
+	query := `SELECT ` + columns + ` FROM users WHERE id = $1`
+	if len(sets) > 0 {
+		query = `UPDATE users SET ` + strings.Join(sets, ", ") + ` WHERE id = $1 RETURNING ` + columns
+	}
+
+	var m models.User
+	if err := tx.Get(&m, query, args...); err != nil {
+		a.logger.WithError(err).Error("Error while updating a user!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.WithError(err).Error("Error while committing a transaction!")
+		return nil, domain.ErrInternalDatabase
+	}
+
 	return m.Domain(), nil
 }
 
@@ -211,8 +251,8 @@ func (a *adapter) GetUserByPhone(phone string) (*domain.User, error) {
 	var m models.User
 	if err := a.db.Get(
 		&m,
-		`SELECT id, status, phone, first_name, middle_name, last_name, city, birthday, email,
-       					created_at, updated_at FROM users WHERE phone = $1`,
+		`SELECT id, status, phone, first_name, middle_name, last_name, city, birthday, email, avatar,
+       					status_reason, status_expires_at, created_at, updated_at FROM users WHERE phone = $1`,
 		phone,
 	); err != nil {
 		a.logger.WithError(err).Error("Error while trying to get a user by phone!")
@@ -222,41 +262,143 @@ func (a *adapter) GetUserByPhone(phone string) (*domain.User, error) {
 	return m.Domain(), nil
 }
 
-func (a *adapter) CreateRefreshSession(userID int, fingerprint, userAgent, ip string, expiresAt time.Time) (uuid.UUID, error) {
-	var refreshToken uuid.UUID
+// IsPhoneRegistered ignores unfinished registrations.
+func (a *adapter) IsPhoneRegistered(phone string) (bool, error) {
+	var registered bool
+	if err := a.db.QueryRowx(
+		`SELECT EXISTS(SELECT 1 FROM users WHERE phone = $1 AND status & B'00000100' = B'00000100')`,
+		phone,
+	).Scan(&registered); err != nil {
+		a.logger.WithError(err).Error("Error while checking a phone!")
+		return false, domain.ErrInternalDatabase
+	}
+
+	return registered, nil
+}
+
+// ChangePhone moves the user to the new phone and records the change in the history.
+// An unfinished registration of the new phone is dropped, its number is proven to be owned by the user.
+func (a *adapter) ChangePhone(userID int, oldPhone, newPhone string, oldConfirmedBy domain.OTPChannel) error {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while starting a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	//noinspection ALL
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`DELETE FROM users WHERE phone = $1 AND status & B'00000100' != B'00000100'`,
+		newPhone,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while deleting an unfinished registration!")
+		return domain.ErrInternalDatabase
+	}
+
+	res, err := tx.Exec(
+		`UPDATE users SET phone = $3 WHERE id = $1 AND phone = $2`,
+		userID,
+		oldPhone,
+		newPhone,
+	)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == "23505" {
+			return domain.ErrUserAlreadyExists
+		}
+
+		a.logger.WithError(err).Error("Error while changing a phone!")
+		return domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while changing a phone!")
+		return domain.ErrInternalDatabase
+	}
+
+	// The phone is changed by another request meanwhile
+	if rowsAffected != 1 {
+		return domain.ErrInvalidPhoneChangeOrder
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO phone_history (user_id, old_phone, new_phone, old_confirmed_by) VALUES ($1, $2, $3, $4)`,
+		userID,
+		oldPhone,
+		newPhone,
+		oldConfirmedBy,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while recording a phone change!")
+		return domain.ErrInternalDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.WithError(err).Error("Error while committing a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) CreateRefreshSession(
+	userID int,
+	clientType domain.ClientType,
+	fingerprint, userAgent, ip string,
+	expiresAt time.Time,
+) (int, uuid.UUID, uuid.UUID, error) {
+	var (
+		id           int
+		refreshToken uuid.UUID
+		familyID     uuid.UUID
+	)
 	if err := a.db.QueryRowx(
-		`INSERT INTO refresh_sessions (user_id, fingerprint, user_agent, ip, expires_at)
-				VALUES ($1, $2, $3, $4, $5)
-				RETURNING refresh_token`,
+		`INSERT INTO refresh_sessions (user_id, client_type, fingerprint, user_agent, ip, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id, refresh_token, family_id`,
 		userID,
+		clientType,
 		fingerprint,
 		userAgent,
 		ip,
 		expiresAt,
-	).Scan(&refreshToken); err != nil {
+	).Scan(&id, &refreshToken, &familyID); err != nil {
 		a.logger.WithError(err).Error("Error while trying to create a new refresh session!")
-		return uuid.Nil, domain.ErrInternalDatabase
+		return 0, uuid.Nil, uuid.Nil, domain.ErrInternalDatabase
 	}
 
-	return refreshToken, nil
+	return id, refreshToken, familyID, nil
 }
 
 func (a *adapter) GetRefreshSessionByToken(token string) (*domain.RefreshSession, error) {
+	if _, err := uuid.Parse(token); err != nil {
+		return nil, domain.ErrUnauthorized
+	}
+
 	var m models.RefreshSession
 	if err := a.db.Get(
 		&m,
 		`SELECT id,
 				       user_id,
+				       client_type,
 				       refresh_token,
 				       fingerprint,
 				       user_agent,
 				       ip,
 				       expires_at,
-				       created_at
+				       created_at,
+				       family_id,
+				       parent_id,
+				       rotated_at,
+				       auth_time
 				FROM refresh_sessions
 				WHERE refresh_token = $1`,
 		token,
 	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrUnauthorized
+		}
+
 		a.logger.WithError(err).Error("Error while trying to get a refresh session by token!")
 		return nil, domain.ErrInternalDatabase
 	}
@@ -264,6 +406,108 @@ func (a *adapter) GetRefreshSessionByToken(token string) (*domain.RefreshSession
 	return m.Domain(), nil
 }
 
+func (a *adapter) RotateRefreshSession(
+	parent *domain.RefreshSession,
+	fingerprint, userAgent, ip string,
+	expiresAt time.Time,
+) (int, uuid.UUID, error) {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while starting a transaction!")
+		return 0, uuid.Nil, domain.ErrInternalDatabase
+	}
+
+	//noinspection ALL
+	defer tx.Rollback()
+
+	// Only one request rotates a token, others are reuses
+	res, err := tx.Exec(
+		`UPDATE refresh_sessions SET rotated_at = now(), expires_at = now()
+				WHERE id = $1 AND rotated_at IS NULL`,
+		parent.ID,
+	)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while rotating a refresh session!")
+		return 0, uuid.Nil, domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while rotating a refresh session!")
+		return 0, uuid.Nil, domain.ErrInternalDatabase
+	}
+
+	if rowsAffected != 1 {
+		return 0, uuid.Nil, domain.ErrRefreshTokenReused
+	}
+
+	var (
+		id           int
+		refreshToken uuid.UUID
+	)
+	if err := tx.QueryRowx(
+		`INSERT INTO refresh_sessions
+				    (user_id, client_type, fingerprint, user_agent, ip, expires_at, family_id, parent_id, auth_time)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				RETURNING id, refresh_token`,
+		parent.UserID,
+		parent.ClientType,
+		fingerprint,
+		userAgent,
+		ip,
+		expiresAt,
+		parent.FamilyID,
+		parent.ID,
+		parent.AuthTime,
+	).Scan(&id, &refreshToken); err != nil {
+		a.logger.WithError(err).Error("Error while trying to create a new refresh session!")
+		return 0, uuid.Nil, domain.ErrInternalDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.WithError(err).Error("Error while committing a transaction!")
+		return 0, uuid.Nil, domain.ErrInternalDatabase
+	}
+
+	return id, refreshToken, nil
+}
+
+// UpdateSessionAuthTime marks an active session of the user as authenticated right now.
+func (a *adapter) UpdateSessionAuthTime(userID, sessionID int) (*domain.RefreshSession, error) {
+	var m models.RefreshSession
+	if err := a.db.Get(
+		&m,
+		`UPDATE refresh_sessions SET auth_time = now()
+				WHERE id = $1 AND user_id = $2 AND expires_at > now()
+				RETURNING id, user_id, client_type, refresh_token, fingerprint, user_agent, ip, expires_at, created_at,
+				          family_id, parent_id, rotated_at, auth_time`,
+		sessionID,
+		userID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrUnauthorized
+		}
+
+		a.logger.WithError(err).Error("Error while updating an authentication time of a session!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	return m.Domain(), nil
+}
+
+func (a *adapter) RevokeSessionFamily(familyID uuid.UUID) error {
+	if _, err := a.db.Exec(
+		`UPDATE refresh_sessions SET expires_at = now()
+				WHERE family_id = $1 AND expires_at > now()`,
+		familyID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while revoking a session family!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
 func (a *adapter) RevokeSession(token string) error {
 	if _, err := a.db.Exec(
 		`UPDATE refresh_sessions SET expires_at = now()
@@ -277,19 +521,25 @@ func (a *adapter) RevokeSession(token string) error {
 	return nil
 }
 
-func (a *adapter) RevokeObsoleteSessions(userID int) error {
-	if _, err := a.db.Exec(
+func (a *adapter) RevokeObsoleteSessions(userID int, clientType domain.ClientType, keep int) ([]uuid.UUID, error) {
+	var familyIDs []uuid.UUID
+	if err := a.db.Select(
+		&familyIDs,
 		`UPDATE refresh_sessions SET expires_at = now()
-				WHERE user_id = $1 AND id NOT IN (SELECT id FROM refresh_sessions 
-													WHERE user_id = $1 AND expires_at > now()
-													ORDER BY created_at DESC LIMIT 5)`,
+				WHERE user_id = $1 AND client_type = $2 AND expires_at > now()
+				  AND id NOT IN (SELECT id FROM refresh_sessions
+									WHERE user_id = $1 AND client_type = $2 AND expires_at > now()
+									ORDER BY created_at DESC LIMIT $3)
+				RETURNING family_id`,
 		userID,
+		clientType,
+		keep,
 	); err != nil {
 		a.logger.WithError(err).Error("Error while revoking old sessions!")
-		return domain.ErrInternalDatabase
+		return nil, domain.ErrInternalDatabase
 	}
 
-	return nil
+	return familyIDs, nil
 }
 
 func (a *adapter) RevokeAllSessions(userID int) error {
@@ -306,6 +556,183 @@ func (a *adapter) RevokeAllSessions(userID int) error {
 	return nil
 }
 
+func (a *adapter) GetRolePermissions(roles []string) ([]domain.Permission, error) {
+	var permissions []domain.Permission
+	if err := a.db.Select(
+		&permissions,
+		`SELECT DISTINCT rp.permission FROM role_permissions rp JOIN roles r ON r.id = rp.role_id
+				WHERE r.name = ANY($1)`,
+		roles,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting permissions of roles!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	return permissions, nil
+}
+
+func (a *adapter) SearchUsers(query string, limit, offset int) ([]*domain.User, error) {
+	var ms []models.User
+	if err := a.db.Select(
+		&ms,
+		`SELECT id, status, phone, first_name, middle_name, last_name, city, birthday, email, avatar,
+				    status_reason, status_expires_at,
+				    ARRAY(SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				    	WHERE ur.user_id = users.id ORDER BY r.name) AS roles,
+				    created_at, updated_at
+				FROM users
+				WHERE $1 = ''
+				   OR id::TEXT = $1
+				   OR phone LIKE '%' || $1 || '%'
+				   OR email ILIKE '%' || $1 || '%'
+				   OR concat_ws(' ', last_name, first_name, middle_name) ILIKE '%' || $1 || '%'
+				ORDER BY id
+				LIMIT $2 OFFSET $3`,
+		query,
+		limit,
+		offset,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while searching users!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	users := make([]*domain.User, 0, len(ms))
+	for i := range ms {
+		users = append(users, ms[i].Domain())
+	}
+
+	return users, nil
+}
+
+var userStateBits = map[domain.AccountState]string{
+	domain.AccountStateActive:          "00000000",
+	domain.AccountStateSuspended:       "00100000",
+	domain.AccountStateBanned:          "01000000",
+	domain.AccountStatePendingDeletion: "10000000",
+}
+
+func (a *adapter) SetUserState(userID int, r *domain.UserStateRequest) error {
+	var reason sql.NullString
+	if r.Reason != "" {
+		reason = sql.NullString{String: r.Reason, Valid: true}
+	}
+
+	res, err := a.db.Exec(
+		`UPDATE users
+				SET status            = status & ~B'11100000' | $2::BIT(8),
+				    status_reason     = $3,
+				    status_expires_at = $4
+				WHERE id = $1`,
+		userID,
+		userStateBits[r.State],
+		reason,
+		r.ExpiresAt,
+	)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while updating a user state!")
+		return domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while updating a user state!")
+		return domain.ErrInternalDatabase
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (a *adapter) GetActiveSessions(userID int) ([]*domain.RefreshSession, error) {
+	var ms []models.RefreshSession
+	if err := a.db.Select(
+		&ms,
+		`SELECT id, user_id, client_type, refresh_token, fingerprint, user_agent, ip, expires_at, created_at
+				FROM refresh_sessions
+				WHERE user_id = $1 AND expires_at > now()
+				ORDER BY created_at DESC`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting active sessions!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	sessions := make([]*domain.RefreshSession, 0, len(ms))
+	for i := range ms {
+		sessions = append(sessions, ms[i].Domain())
+	}
+
+	return sessions, nil
+}
+
+func (a *adapter) GetKnownDevices(userID int) ([]*domain.KnownDevice, error) {
+	var ms []models.KnownDevice
+	if err := a.db.Select(
+		&ms,
+		`SELECT id, user_id, fingerprint, user_agent, ip, city, first_seen_at, last_seen_at
+				FROM known_devices WHERE user_id = $1 ORDER BY last_seen_at DESC`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting known devices!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	devices := make([]*domain.KnownDevice, 0, len(ms))
+	for i := range ms {
+		devices = append(devices, ms[i].Domain())
+	}
+
+	return devices, nil
+}
+
+// SaveKnownDevice adds a device or updates the last seen IP and city of a known one.
+func (a *adapter) SaveKnownDevice(d *domain.KnownDevice) error {
+	var city sql.NullString
+	if d.City != "" {
+		city = sql.NullString{String: d.City, Valid: true}
+	}
+
+	if _, err := a.db.Exec(
+		`INSERT INTO known_devices (user_id, fingerprint, user_agent, ip, city) VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (user_id, fingerprint) DO UPDATE
+				SET user_agent = excluded.user_agent, ip = excluded.ip,
+				    city = COALESCE(excluded.city, known_devices.city), last_seen_at = now()`,
+		d.UserID,
+		d.Fingerprint,
+		d.UserAgent,
+		d.IP,
+		city,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while saving a known device!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) RevokeUserSession(userID, sessionID int) (uuid.UUID, error) {
+	var familyID uuid.UUID
+	if err := a.db.QueryRow(
+		`UPDATE refresh_sessions SET expires_at = now()
+				WHERE id = $1 AND user_id = $2 AND expires_at > now()
+				RETURNING family_id`,
+		sessionID,
+		userID,
+	).Scan(&familyID); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, domain.ErrSessionNotFound
+		}
+
+		a.logger.WithError(err).Error("Error while revoking a session!")
+		return uuid.Nil, domain.ErrInternalDatabase
+	}
+
+	return familyID, nil
+}
+
 func (a *adapter) UpdateEmail(userID int, email string) error {
 	var currentEmail sql.NullString
 	if err := a.db.QueryRow(
@@ -333,6 +760,27 @@ func (a *adapter) UpdateEmail(userID int, email string) error {
 	return nil
 }
 
+// UpdateAvatar sets the key prefix of the avatar variants, nil removes the avatar.
+func (a *adapter) UpdateAvatar(userID int, avatar *string) error {
+	res, err := a.db.Exec(`UPDATE users SET avatar = $2 WHERE id = $1`, userID, avatar)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while updating an avatar!")
+		return domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while updating an avatar!")
+		return domain.ErrInternalDatabase
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (a *adapter) ConfirmEmail(emailAddress string) error {
 	if _, err := a.db.Exec(
 		`UPDATE users SET status = status | B'00010000'
@@ -345,3 +793,741 @@ func (a *adapter) ConfirmEmail(emailAddress string) error {
 
 	return nil
 }
+
+// GetUserSessions returns every session of the user kept so far, refresh tokens aren't selected.
+func (a *adapter) GetUserSessions(userID int) ([]*domain.RefreshSession, error) {
+	var ms []models.RefreshSession
+	if err := a.db.Select(
+		&ms,
+		`SELECT id, user_id, client_type, fingerprint, user_agent, ip, expires_at, created_at,
+				       family_id, parent_id, rotated_at, auth_time
+				FROM refresh_sessions
+				WHERE user_id = $1
+				ORDER BY created_at DESC`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting sessions of a user!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	sessions := make([]*domain.RefreshSession, 0, len(ms))
+	for i := range ms {
+		sessions = append(sessions, ms[i].Domain())
+	}
+
+	return sessions, nil
+}
+
+func (a *adapter) GetUserAuditEvents(userID int) ([]*domain.AuditEvent, error) {
+	var ms []models.AuditEvent
+	if err := a.db.Select(
+		&ms,
+		`SELECT id, type, user_id, client_id, ip, user_agent, details, created_at
+				FROM audit_events
+				WHERE user_id = $1
+				ORDER BY created_at DESC`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting audit events of a user!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	events := make([]*domain.AuditEvent, 0, len(ms))
+	for i := range ms {
+		events = append(events, ms[i].Domain())
+	}
+
+	return events, nil
+}
+
+// GetUserMessages returns messages sent to the user, payloads with codes and links aren't selected.
+func (a *adapter) GetUserMessages(userID int) ([]*domain.OutboundMessage, error) {
+	var ms []models.OutboundMessage
+	if err := a.db.Select(
+		&ms,
+		`SELECT id, kind, user_id, recipient, request_id, status, attempts, next_attempt_at, expires_at,
+				       last_error, provider, provider_message_id, sent_at, created_at
+				FROM outbox_messages
+				WHERE user_id = $1
+				ORDER BY created_at DESC`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting messages of a user!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	messages := make([]*domain.OutboundMessage, 0, len(ms))
+	for i := range ms {
+		messages = append(messages, ms[i].Domain())
+	}
+
+	return messages, nil
+}
+
+// GetUsersToPurge returns accounts pending deletion whose grace period is over.
+func (a *adapter) GetUsersToPurge(limit int) ([]int, error) {
+	var ids []int
+	if err := a.db.Select(
+		&ids,
+		`SELECT id FROM users
+				WHERE status & B'10000000' = B'10000000' AND status_expires_at <= now()
+				ORDER BY status_expires_at
+				LIMIT $1`,
+		limit,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting users to purge!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	return ids, nil
+}
+
+// PurgeUser deletes the account with everything referencing it or, when anonymize is set, keeps a stripped
+// row for the audit events. Messages go in both cases.
+func (a *adapter) PurgeUser(userID int, anonymize bool) error {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while starting a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	//noinspection ALL
+	defer tx.Rollback()
+
+	// The deletion may have been cancelled meanwhile
+	var m models.User
+	if err := tx.Get(
+		&m,
+		`SELECT id FROM users
+				WHERE id = $1 AND status & B'10000000' = B'10000000' AND status_expires_at <= now()
+				FOR UPDATE`,
+		userID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+
+		a.logger.WithError(err).Error("Error while getting a user to purge!")
+		return domain.ErrInternalDatabase
+	}
+
+	if _, err := tx.Exec(`DELETE FROM outbox_messages WHERE user_id = $1`, userID); err != nil {
+		a.logger.WithError(err).Error("Error while deleting messages of a user!")
+		return domain.ErrInternalDatabase
+	}
+
+	// Audit events outlive the user, but not the phones in them
+	if _, err := tx.Exec(
+		`UPDATE audit_events SET details = details - 'old_phone' - 'new_phone' WHERE user_id = $1`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while scrubbing audit events of a user!")
+		return domain.ErrInternalDatabase
+	}
+
+	if anonymize {
+		if _, err := tx.Exec(
+			`UPDATE users
+					SET phone             = 'deleted:' || id,
+					    first_name        = NULL,
+					    middle_name       = NULL,
+					    last_name         = NULL,
+					    birthday          = NULL,
+					    city              = NULL,
+					    email             = NULL,
+					    avatar            = NULL,
+					    status            = B'10000000',
+					    status_reason     = 'purged',
+					    status_expires_at = NULL
+					WHERE id = $1`,
+			userID,
+		); err != nil {
+			a.logger.WithError(err).Error("Error while anonymizing a user!")
+			return domain.ErrInternalDatabase
+		}
+
+		for _, table := range []string{
+			"refresh_sessions",
+			"user_totp",
+			"recovery_codes",
+			"webauthn_credentials",
+			"known_devices",
+			"phone_history",
+			"user_roles",
+		} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = $1`, userID); err != nil {
+				a.logger.WithError(err).WithField("table", table).Error("Error while deleting data of a user!")
+				return domain.ErrInternalDatabase
+			}
+		}
+	} else if _, err := tx.Exec(`DELETE FROM users WHERE id = $1`, userID); err != nil {
+		a.logger.WithError(err).Error("Error while deleting a user!")
+		return domain.ErrInternalDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.WithError(err).Error("Error while committing a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) EnqueueOutboundMessage(m *domain.OutboundMessage) (int, error) {
+	payload, err := json.Marshal(m.Payload)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while marshalling an outbound message payload!")
+		return 0, domain.ErrInternalDatabase
+	}
+
+	var id int
+	if err := a.db.QueryRowx(
+		`INSERT INTO outbox_messages (kind, user_id, recipient, request_id, payload, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id`,
+		m.Kind,
+		m.UserID,
+		m.Recipient,
+		m.RequestID,
+		string(payload),
+		m.ExpiresAt,
+	).Scan(&id); err != nil {
+		a.logger.WithError(err).Error("Error while enqueueing an outbound message!")
+		return 0, domain.ErrInternalDatabase
+	}
+
+	return id, nil
+}
+
+func (a *adapter) ClaimOutboundMessages(limit int, lease time.Duration) ([]*domain.OutboundMessage, error) {
+	// Messages of crashed workers are claimed again when their lease is over
+	var ms []models.OutboundMessage
+	if err := a.db.Select(
+		&ms,
+		`UPDATE outbox_messages
+				SET status       = 'processing',
+				    attempts     = attempts + 1,
+				    locked_until = now() + make_interval(secs => $2)
+				WHERE id IN (SELECT id FROM outbox_messages
+								WHERE (status = 'pending' AND next_attempt_at <= now())
+								   OR (status = 'processing' AND locked_until < now())
+								ORDER BY next_attempt_at
+								LIMIT $1 FOR UPDATE SKIP LOCKED)
+				RETURNING id,
+				          kind,
+				          recipient,
+				          request_id,
+				          payload,
+				          status,
+				          attempts,
+				          next_attempt_at,
+				          expires_at,
+				          last_error,
+				          provider,
+				          provider_message_id,
+				          sent_at,
+				          created_at`,
+		limit,
+		lease.Seconds(),
+	); err != nil {
+		a.logger.WithError(err).Error("Error while claiming outbound messages!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	messages := make([]*domain.OutboundMessage, 0, len(ms))
+	for i := range ms {
+		messages = append(messages, ms[i].Domain())
+	}
+
+	return messages, nil
+}
+
+func (a *adapter) MarkOutboundMessageSent(id int, provider, providerMessageID string) error {
+	if _, err := a.db.Exec(
+		`UPDATE outbox_messages
+				SET status              = 'sent',
+				    provider            = $2,
+				    provider_message_id = NULLIF($3, ''),
+				    locked_until        = NULL,
+				    sent_at             = now()
+				WHERE id = $1`,
+		id,
+		provider,
+		providerMessageID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while marking an outbound message as sent!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) MarkOutboundMessageFailed(id int, lastError string, nextAttemptAt time.Time) error {
+	if _, err := a.db.Exec(
+		`UPDATE outbox_messages
+				SET status          = 'pending',
+				    last_error      = $2,
+				    next_attempt_at = $3,
+				    locked_until    = NULL
+				WHERE id = $1`,
+		id,
+		lastError,
+		nextAttemptAt,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while marking an outbound message as failed!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) MarkOutboundMessageDead(id int, lastError string) error {
+	if _, err := a.db.Exec(
+		`UPDATE outbox_messages
+				SET status       = 'dead',
+				    last_error   = $2,
+				    locked_until = NULL
+				WHERE id = $1`,
+		id,
+		lastError,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while moving an outbound message to the dead letters!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) StoreDeliveryReceipt(r *domain.DeliveryReceipt) error {
+	payload := r.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	res, err := a.db.Exec(
+		`INSERT INTO delivery_receipts (outbound_message_id, status, error_code, payload)
+				SELECT id, $3, NULLIF($4, ''), $5
+				FROM outbox_messages
+				WHERE provider = $1 AND provider_message_id = $2`,
+		r.Provider,
+		r.ProviderMessageID,
+		r.Status,
+		r.ErrorCode,
+		string(payload),
+	)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while storing a delivery receipt!")
+		return domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while storing a delivery receipt!")
+		return domain.ErrInternalDatabase
+	}
+
+	if rowsAffected == 0 {
+		return domain.ErrOutboundMessageNotFound
+	}
+
+	return nil
+}
+
+// GetOTPDeliveryStatus finds the last message of the request, of the given user only if one is set.
+func (a *adapter) GetOTPDeliveryStatus(requestID uuid.UUID, userID *int) (*domain.OTPDeliveryStatus, error) {
+	var m models.OTPDeliveryStatus
+	if err := a.db.Get(
+		&m,
+		`SELECT m.status,
+				       COALESCE(r.status, 'unknown') AS delivery_status,
+				       m.provider,
+				       r.error_code,
+				       m.sent_at,
+				       GREATEST(m.created_at, m.updated_at, r.created_at) AS updated_at
+				FROM outbox_messages m
+				         LEFT JOIN LATERAL (SELECT status, error_code, created_at
+				                            FROM delivery_receipts
+				                            WHERE outbound_message_id = m.id
+				                            ORDER BY created_at DESC
+				                            LIMIT 1) r ON TRUE
+				WHERE m.request_id = $1
+				  AND ($2::INTEGER IS NULL OR m.user_id = $2)
+				ORDER BY m.created_at DESC
+				LIMIT 1`,
+		requestID,
+		userID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOutboundMessageNotFound
+		}
+
+		a.logger.WithError(err).Error("Error while getting an OTP delivery status!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	return m.Domain(), nil
+}
+
+func (a *adapter) CreateTOTP(userID int, secret string) error {
+	// A pending enrollment is replaced, a confirmed one is kept
+	res, err := a.db.Exec(
+		`INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+				ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, created_at = now()
+				WHERE user_totp.confirmed_at IS NULL`,
+		userID,
+		secret,
+	)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while creating a TOTP!")
+		return domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while creating a TOTP!")
+		return domain.ErrInternalDatabase
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+func (a *adapter) GetTOTP(userID int) (*domain.TOTP, error) {
+	var m models.TOTP
+	if err := a.db.Get(
+		&m,
+		`SELECT user_id, secret, confirmed_at, created_at FROM user_totp WHERE user_id = $1`,
+		userID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrTOTPNotEnabled
+		}
+
+		a.logger.WithError(err).Error("Error while getting a TOTP!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	return m.Domain(), nil
+}
+
+func (a *adapter) ConfirmTOTP(userID int, recoveryCodeHashes []string) error {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while starting a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	//noinspection ALL
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`UPDATE user_totp SET confirmed_at = now() WHERE user_id = $1`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while confirming a TOTP!")
+		return domain.ErrInternalDatabase
+	}
+
+	if _, err := tx.Exec(
+		`DELETE FROM recovery_codes WHERE user_id = $1`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while deleting old recovery codes!")
+		return domain.ErrInternalDatabase
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(
+			`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID,
+			hash,
+		); err != nil {
+			a.logger.WithError(err).Error("Error while storing a recovery code!")
+			return domain.ErrInternalDatabase
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.WithError(err).Error("Error while committing a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) DeleteTOTP(userID int) error {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while starting a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	//noinspection ALL
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		a.logger.WithError(err).Error("Error while deleting recovery codes!")
+		return domain.ErrInternalDatabase
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		a.logger.WithError(err).Error("Error while deleting a TOTP!")
+		return domain.ErrInternalDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.WithError(err).Error("Error while committing a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) UseRecoveryCode(userID int, codeHash string) error {
+	res, err := a.db.Exec(
+		`UPDATE recovery_codes SET used_at = now()
+				WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID,
+		codeHash,
+	)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while using a recovery code!")
+		return domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while using a recovery code!")
+		return domain.ErrInternalDatabase
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+func (a *adapter) UseTOTPStep(userID int, step int64) error {
+	res, err := a.db.Exec(
+		`UPDATE user_totp SET last_used_step = $2
+				WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`,
+		userID,
+		step,
+	)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while using a TOTP step!")
+		return domain.ErrInternalDatabase
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while using a TOTP step!")
+		return domain.ErrInternalDatabase
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrTOTPCodeReused
+	}
+
+	return nil
+}
+
+func (a *adapter) CreateWebAuthnCredential(c *domain.WebAuthnCredential) error {
+	if _, err := a.db.Exec(
+		`INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, aaguid)
+				VALUES ($1, $2, $3, $4, $5)`,
+		c.UserID,
+		c.CredentialID,
+		c.PublicKey,
+		int64(c.SignCount),
+		c.AAGUID,
+	); err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == "23505" {
+			return domain.ErrWebAuthnCredentialRegistered
+		}
+
+		a.logger.WithError(err).Error("Error while creating a WebAuthn credential!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) GetWebAuthnCredentials(userID int) ([]*domain.WebAuthnCredential, error) {
+	var ms []models.WebAuthnCredential
+	if err := a.db.Select(
+		&ms,
+		`SELECT id, user_id, credential_id, public_key, sign_count, aaguid, last_used_at, created_at
+				FROM webauthn_credentials WHERE user_id = $1 ORDER BY id`,
+		userID,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting WebAuthn credentials!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	credentials := make([]*domain.WebAuthnCredential, 0, len(ms))
+	for i := range ms {
+		credentials = append(credentials, ms[i].Domain())
+	}
+
+	return credentials, nil
+}
+
+func (a *adapter) GetWebAuthnCredential(credentialID []byte) (*domain.WebAuthnCredential, error) {
+	var m models.WebAuthnCredential
+	if err := a.db.Get(
+		&m,
+		`SELECT id, user_id, credential_id, public_key, sign_count, aaguid, last_used_at, created_at
+				FROM webauthn_credentials WHERE credential_id = $1`,
+		credentialID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrWebAuthnCredentialNotFound
+		}
+
+		a.logger.WithError(err).Error("Error while getting a WebAuthn credential!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	return m.Domain(), nil
+}
+
+func (a *adapter) UpdateWebAuthnSignCount(id int, signCount uint32) error {
+	if _, err := a.db.Exec(
+		`UPDATE webauthn_credentials SET sign_count = $2, last_used_at = now() WHERE id = $1`,
+		id,
+		int64(signCount),
+	); err != nil {
+		a.logger.WithError(err).Error("Error while updating a WebAuthn credential!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) GetSigningKeys() ([]*domain.SigningKey, error) {
+	var ms []models.SigningKey
+	if err := a.db.Select(
+		&ms,
+		`SELECT kid, algorithm, private_key, activates_at, expires_at, created_at FROM signing_keys
+				WHERE expires_at IS NULL OR expires_at > now() ORDER BY activates_at`,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while getting signing keys!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	keys := make([]*domain.SigningKey, 0, len(ms))
+	for i := range ms {
+		keys = append(keys, ms[i].Domain())
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKey adds a key if the newest one is still previousKID, so that concurrent instances rotate once.
+// Keys without expiration get retireAt.
+func (a *adapter) RotateSigningKey(k *domain.SigningKey, previousKID string, retireAt time.Time) error {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while starting a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	//noinspection ALL
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('signing_keys'))`); err != nil {
+		a.logger.WithError(err).Error("Error while locking signing keys!")
+		return domain.ErrInternalDatabase
+	}
+
+	var newestKID string
+	if err := tx.QueryRowx(
+		`SELECT kid FROM signing_keys ORDER BY activates_at DESC LIMIT 1`,
+	).Scan(&newestKID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		a.logger.WithError(err).Error("Error while getting the newest signing key!")
+		return domain.ErrInternalDatabase
+	}
+
+	// Another instance has already rotated the key
+	if newestKID != previousKID {
+		return nil
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE signing_keys SET expires_at = $1 WHERE expires_at IS NULL`,
+		retireAt,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while retiring signing keys!")
+		return domain.ErrInternalDatabase
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO signing_keys (kid, algorithm, private_key, activates_at) VALUES ($1, $2, $3, $4)`,
+		k.KID,
+		k.Algorithm,
+		k.PrivateKey,
+		k.ActivatesAt,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while creating a signing key!")
+		return domain.ErrInternalDatabase
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.WithError(err).Error("Error while committing a transaction!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
+
+func (a *adapter) GetOAuthClient(clientID string) (*domain.OAuthClient, error) {
+	var m models.OAuthClient
+	if err := a.db.Get(
+		&m,
+		`SELECT client_id, name, secret_hash, tls_client_auth_subject, redirect_uris, scopes, grant_types, created_at
+				FROM oauth_clients WHERE client_id = $1`,
+		clientID,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOAuthInvalidClient
+		}
+
+		a.logger.WithError(err).Error("Error while getting an OAuth client!")
+		return nil, domain.ErrInternalDatabase
+	}
+
+	return m.Domain(), nil
+}
+
+func (a *adapter) CreateAuditEvent(e *domain.AuditEvent) error {
+	details := e.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while marshalling audit event details!")
+		return domain.ErrInternalDatabase
+	}
+
+	if _, err := a.db.Exec(
+		`INSERT INTO audit_events (type, user_id, client_id, ip, user_agent, details) VALUES ($1, $2, $3, $4, $5, $6)`,
+		e.Type,
+		e.UserID,
+		e.ClientID,
+		e.IP,
+		e.UserAgent,
+		detailsJSON,
+	); err != nil {
+		a.logger.WithError(err).Error("Error while creating an audit event!")
+		return domain.ErrInternalDatabase
+	}
+
+	return nil
+}
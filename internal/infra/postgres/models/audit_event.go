@@ -0,0 +1,39 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+type AuditEvent struct {
+	ID        int            `db:"id"`
+	Type      string         `db:"type"`
+	UserID    sql.NullInt32  `db:"user_id"`
+	ClientID  sql.NullString `db:"client_id"`
+	IP        sql.NullString `db:"ip"`
+	UserAgent sql.NullString `db:"user_agent"`
+	Details   []byte         `db:"details"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func (e *AuditEvent) Domain() *domain.AuditEvent {
+	d := &domain.AuditEvent{
+		ID:        e.ID,
+		Type:      domain.AuditEventType(e.Type),
+		IP:        e.IP.String,
+		UserAgent: e.UserAgent.String,
+		CreatedAt: e.CreatedAt,
+	}
+	_ = json.Unmarshal(e.Details, &d.Details)
+	if e.UserID.Valid {
+		userID := int(e.UserID.Int32)
+		d.UserID = &userID
+	}
+	if e.ClientID.Valid {
+		d.ClientID = &e.ClientID.String
+	}
+
+	return d
+}
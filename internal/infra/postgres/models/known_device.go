@@ -0,0 +1,31 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+type KnownDevice struct {
+	ID          int            `db:"id"`
+	UserID      int            `db:"user_id"`
+	Fingerprint string         `db:"fingerprint"`
+	UserAgent   string         `db:"user_agent"`
+	IP          string         `db:"ip"`
+	City        sql.NullString `db:"city"`
+	FirstSeenAt time.Time      `db:"first_seen_at"`
+	LastSeenAt  time.Time      `db:"last_seen_at"`
+}
+
+func (d *KnownDevice) Domain() *domain.KnownDevice {
+	return &domain.KnownDevice{
+		ID:          d.ID,
+		UserID:      d.UserID,
+		Fingerprint: d.Fingerprint,
+		UserAgent:   d.UserAgent,
+		IP:          d.IP,
+		City:        d.City.String,
+		FirstSeenAt: d.FirstSeenAt,
+		LastSeenAt:  d.LastSeenAt,
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/jackc/pgtype"
+)
+
+type OAuthClient struct {
+	ClientID             string           `db:"client_id"`
+	Name                 string           `db:"name"`
+	SecretHash           sql.NullString   `db:"secret_hash"`
+	TLSClientAuthSubject sql.NullString   `db:"tls_client_auth_subject"`
+	RedirectURIs         pgtype.TextArray `db:"redirect_uris"`
+	Scopes               pgtype.TextArray `db:"scopes"`
+	GrantTypes           pgtype.TextArray `db:"grant_types"`
+	CreatedAt            time.Time        `db:"created_at"`
+}
+
+func (c *OAuthClient) Domain() *domain.OAuthClient {
+	d := &domain.OAuthClient{
+		ClientID:  c.ClientID,
+		Name:      c.Name,
+		CreatedAt: c.CreatedAt,
+	}
+	if c.SecretHash.Valid {
+		d.SecretHash = &c.SecretHash.String
+	}
+	if c.TLSClientAuthSubject.Valid {
+		d.TLSClientAuthSubject = &c.TLSClientAuthSubject.String
+	}
+	_ = c.RedirectURIs.AssignTo(&d.RedirectURIs)
+	_ = c.Scopes.AssignTo(&d.Scopes)
+	_ = c.GrantTypes.AssignTo(&d.GrantTypes)
+
+	return d
+}
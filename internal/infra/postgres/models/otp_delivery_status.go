@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+type OTPDeliveryStatus struct {
+	MessageStatus  string         `db:"status"`
+	DeliveryStatus string         `db:"delivery_status"`
+	Provider       sql.NullString `db:"provider"`
+	ErrorCode      sql.NullString `db:"error_code"`
+	SentAt         sql.NullTime   `db:"sent_at"`
+	UpdatedAt      time.Time      `db:"updated_at"`
+}
+
+func (m *OTPDeliveryStatus) Domain() *domain.OTPDeliveryStatus {
+	d := &domain.OTPDeliveryStatus{
+		MessageStatus:  domain.OutboundMessageStatus(m.MessageStatus),
+		DeliveryStatus: domain.DeliveryStatus(m.DeliveryStatus),
+		UpdatedAt:      m.UpdatedAt,
+	}
+	if m.Provider.Valid {
+		d.Provider = &m.Provider.String
+	}
+	if m.ErrorCode.Valid {
+		d.ErrorCode = &m.ErrorCode.String
+	}
+	if m.SentAt.Valid {
+		d.SentAt = &m.SentAt.Time
+	}
+
+	return d
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+type OutboundMessage struct {
+	ID                int            `db:"id"`
+	Kind              string         `db:"kind"`
+	UserID            sql.NullInt32  `db:"user_id"`
+	Recipient         string         `db:"recipient"`
+	RequestID         sql.NullString `db:"request_id"`
+	Payload           []byte         `db:"payload"`
+	Status            string         `db:"status"`
+	Attempts          int            `db:"attempts"`
+	NextAttemptAt     time.Time      `db:"next_attempt_at"`
+	ExpiresAt         sql.NullTime   `db:"expires_at"`
+	LastError         sql.NullString `db:"last_error"`
+	Provider          sql.NullString `db:"provider"`
+	ProviderMessageID sql.NullString `db:"provider_message_id"`
+	SentAt            sql.NullTime   `db:"sent_at"`
+	CreatedAt         time.Time      `db:"created_at"`
+}
+
+func (m *OutboundMessage) Domain() *domain.OutboundMessage {
+	d := &domain.OutboundMessage{
+		ID:            m.ID,
+		Kind:          domain.OutboundMessageKind(m.Kind),
+		Recipient:     m.Recipient,
+		Status:        domain.OutboundMessageStatus(m.Status),
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		CreatedAt:     m.CreatedAt,
+	}
+	_ = json.Unmarshal(m.Payload, &d.Payload)
+	if m.UserID.Valid {
+		userID := int(m.UserID.Int32)
+		d.UserID = &userID
+	}
+	if m.RequestID.Valid {
+		if requestID, err := uuid.Parse(m.RequestID.String); err == nil {
+			d.RequestID = &requestID
+		}
+	}
+	if m.ExpiresAt.Valid {
+		d.ExpiresAt = &m.ExpiresAt.Time
+	}
+	if m.LastError.Valid {
+		d.LastError = &m.LastError.String
+	}
+	if m.Provider.Valid {
+		d.Provider = &m.Provider.String
+	}
+	if m.ProviderMessageID.Valid {
+		d.ProviderMessageID = &m.ProviderMessageID.String
+	}
+	if m.SentAt.Valid {
+		d.SentAt = &m.SentAt.Time
+	}
+
+	return d
+}
@@ -1,30 +1,51 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 	"trainee-assignment-backend/internal/domain"
+
+	"github.com/google/uuid"
 )
 
 type RefreshSession struct {
 	ID           int       `db:"id"`
 	UserID       int       `db:"user_id"`
+	ClientType   string    `db:"client_type"`
 	RefreshToken string    `db:"refresh_token"`
 	Fingerprint  string    `db:"fingerprint"`
 	UserAgent    string    `db:"user_agent"`
 	IP           string    `db:"ip"`
 	ExpiresAt    time.Time `db:"expires_at"`
 	CreatedAt    time.Time `db:"created_at"`
+
+	FamilyID  uuid.UUID     `db:"family_id"`
+	ParentID  sql.NullInt32 `db:"parent_id"`
+	RotatedAt sql.NullTime  `db:"rotated_at"`
+	AuthTime  time.Time     `db:"auth_time"`
 }
 
 func (u *RefreshSession) Domain() *domain.RefreshSession {
-	return &domain.RefreshSession{
+	d := &domain.RefreshSession{
 		ID:           u.ID,
 		UserID:       u.UserID,
+		ClientType:   domain.ClientType(u.ClientType),
 		RefreshToken: u.RefreshToken,
 		Fingerprint:  u.Fingerprint,
 		UserAgent:    u.UserAgent,
 		IP:           u.IP,
 		ExpiresAt:    u.ExpiresAt,
 		CreatedAt:    u.CreatedAt,
+		FamilyID:     u.FamilyID,
+		AuthTime:     u.AuthTime,
+	}
+	if u.ParentID.Valid {
+		parentID := int(u.ParentID.Int32)
+		d.ParentID = &parentID
 	}
+	if u.RotatedAt.Valid {
+		d.RotatedAt = &u.RotatedAt.Time
+	}
+
+	return d
 }
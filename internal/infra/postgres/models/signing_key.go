@@ -0,0 +1,31 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+type SigningKey struct {
+	KID         string       `db:"kid"`
+	Algorithm   string       `db:"algorithm"`
+	PrivateKey  []byte       `db:"private_key"`
+	ActivatesAt time.Time    `db:"activates_at"`
+	ExpiresAt   sql.NullTime `db:"expires_at"`
+	CreatedAt   time.Time    `db:"created_at"`
+}
+
+func (k *SigningKey) Domain() *domain.SigningKey {
+	d := &domain.SigningKey{
+		KID:         k.KID,
+		Algorithm:   k.Algorithm,
+		PrivateKey:  k.PrivateKey,
+		ActivatesAt: k.ActivatesAt,
+		CreatedAt:   k.CreatedAt,
+	}
+	if k.ExpiresAt.Valid {
+		d.ExpiresAt = &k.ExpiresAt.Time
+	}
+
+	return d
+}
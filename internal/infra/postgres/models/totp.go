@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+type TOTP struct {
+	UserID      int          `db:"user_id"`
+	Secret      string       `db:"secret"`
+	ConfirmedAt sql.NullTime `db:"confirmed_at"`
+	CreatedAt   time.Time    `db:"created_at"`
+}
+
+func (t *TOTP) Domain() *domain.TOTP {
+	d := &domain.TOTP{
+		UserID:    t.UserID,
+		Secret:    t.Secret,
+		CreatedAt: t.CreatedAt,
+	}
+	if t.ConfirmedAt.Valid {
+		d.ConfirmedAt = &t.ConfirmedAt.Time
+	}
+
+	return d
+}
@@ -9,17 +9,23 @@ import (
 )
 
 type User struct {
-	ID         int            `db:"id"`
-	Status     pgtype.Bit     `db:"status"`
-	Phone      string         `db:"phone"`
-	FirstName  sql.NullString `db:"first_name"`
-	MiddleName sql.NullString `db:"middle_name"`
-	LastName   sql.NullString `db:"last_name"`
-	Birthday   sql.NullTime   `db:"birthday"`
-	City       sql.NullString `db:"city"`
-	Email      sql.NullString `db:"email"`
-	CreatedAt  time.Time      `db:"created_at"`
-	UpdatedAt  sql.NullTime   `db:"updated_at"`
+	ID         int              `db:"id"`
+	Status     pgtype.Bit       `db:"status"`
+	Phone      string           `db:"phone"`
+	FirstName  sql.NullString   `db:"first_name"`
+	MiddleName sql.NullString   `db:"middle_name"`
+	LastName   sql.NullString   `db:"last_name"`
+	Birthday   sql.NullTime     `db:"birthday"`
+	City       sql.NullString   `db:"city"`
+	Email      sql.NullString   `db:"email"`
+	Roles      pgtype.TextArray `db:"roles"`
+	Avatar     sql.NullString   `db:"avatar"`
+
+	StatusReason    sql.NullString `db:"status_reason"`
+	StatusExpiresAt sql.NullTime   `db:"status_expires_at"`
+
+	CreatedAt time.Time    `db:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at"`
 }
 
 func (u *User) Domain() *domain.User {
@@ -49,6 +55,16 @@ func (u *User) Domain() *domain.User {
 	if u.Email.Valid {
 		d.Email = &u.Email.String
 	}
+	_ = u.Roles.AssignTo(&d.Roles)
+	if u.Avatar.Valid {
+		d.Avatar = &u.Avatar.String
+	}
+	if u.StatusReason.Valid {
+		d.StatusReason = &u.StatusReason.String
+	}
+	if u.StatusExpiresAt.Valid {
+		d.StatusExpiresAt = &u.StatusExpiresAt.Time
+	}
 	if u.UpdatedAt.Valid {
 		d.UpdatedAt = &u.UpdatedAt.Time
 	}
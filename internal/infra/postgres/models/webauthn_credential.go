@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+type WebAuthnCredential struct {
+	ID           int          `db:"id"`
+	UserID       int          `db:"user_id"`
+	CredentialID []byte       `db:"credential_id"`
+	PublicKey    []byte       `db:"public_key"`
+	SignCount    int64        `db:"sign_count"`
+	AAGUID       []byte       `db:"aaguid"`
+	LastUsedAt   sql.NullTime `db:"last_used_at"`
+	CreatedAt    time.Time    `db:"created_at"`
+}
+
+func (c *WebAuthnCredential) Domain() *domain.WebAuthnCredential {
+	d := &domain.WebAuthnCredential{
+		ID:           c.ID,
+		UserID:       c.UserID,
+		CredentialID: c.CredentialID,
+		PublicKey:    c.PublicKey,
+		SignCount:    uint32(c.SignCount),
+		AAGUID:       c.AAGUID,
+		CreatedAt:    c.CreatedAt,
+	}
+	if c.LastUsedAt.Valid {
+		d.LastUsedAt = &c.LastUsedAt.Time
+	}
+
+	return d
+}
@@ -0,0 +1,113 @@
+package purge
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+type adapter struct {
+	logger *logrus.Logger
+	config *Config
+	db     domain.Database
+	blobs  domain.BlobStore
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Creating a new job purging accounts whose deletion grace period is over.
+func NewAdapter(logger *logrus.Logger, config *Config, db domain.Database, blobs domain.BlobStore) domain.Worker {
+	return &adapter{
+		logger: logger,
+		config: config,
+		db:     db,
+		blobs:  blobs,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Run purges accounts every interval until Shutdown.
+func (a *adapter) Run() error {
+	a.logger.WithField("mode", a.config.Mode).Info("Purging deleted accounts.")
+	defer close(a.done)
+
+	for {
+		a.purge()
+
+		select {
+		case <-a.stop:
+			return nil
+		case <-time.After(a.config.Interval):
+		}
+	}
+}
+
+// Shutdown waits for the current batch, the rest is purged after the restart.
+func (a *adapter) Shutdown(ctx context.Context) error {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *adapter) purge() {
+	ids, err := a.db.GetUsersToPurge(a.config.BatchSize)
+	if err != nil {
+		return
+	}
+
+	anonymize := a.config.Mode == "anonymize"
+	for _, id := range ids {
+		select {
+		case <-a.stop:
+			return
+		default:
+		}
+
+		logger := a.logger.WithField("user_id", id)
+
+		// The avatar isn't known once the row is purged
+		user, err := a.db.GetUser(id)
+		if err != nil {
+			continue
+		}
+
+		if err := a.db.PurgeUser(id, anonymize); err != nil {
+			if err != domain.ErrUserNotFound {
+				logger.WithError(err).Error("Error while purging an account!")
+			}
+			continue
+		}
+
+		// The event of a deleted account isn't linked to it anymore
+		event := &domain.AuditEvent{
+			Type:    domain.AuditEventTypeAccountPurged,
+			Details: map[string]string{"user_id": strconv.Itoa(id), "mode": a.config.Mode},
+		}
+		if anonymize {
+			event.UserID = &id
+		}
+		_ = a.db.CreateAuditEvent(event)
+
+		for _, key := range user.AvatarKeys() {
+			if err := a.blobs.Delete(key); err != nil {
+				logger.WithError(err).WithField("key", key).Warn("Error while deleting an avatar!")
+			}
+		}
+
+		logger.Info("Account is purged.")
+	}
+}
@@ -0,0 +1,9 @@
+package purge
+
+import "time"
+
+type Config struct {
+	Interval  time.Duration `long:"interval" env:"INTERVAL" description:"Delay between runs of the purge job" default:"1h"`
+	BatchSize int           `long:"batch-size" env:"BATCH_SIZE" description:"Accounts purged in a run at most" default:"100"`
+	Mode      string        `long:"mode" env:"MODE" description:"Whether accounts are anonymized or deleted" choice:"anonymize" choice:"delete" default:"anonymize"`
+}
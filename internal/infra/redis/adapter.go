@@ -74,11 +74,12 @@ type otpSendRateLimit struct {
 }
 
 type otpCheckRateLimit struct {
-	Code    string `json:"code"`
-	Attempt int    `json:"attempt"`
+	Code    string            `json:"code"`
+	Channel domain.OTPChannel `json:"channel"`
+	Attempt int               `json:"attempt"`
 }
 
-func (a *adapter) Store(otpType domain.OTPType, requestID uuid.UUID, phone, code string) error {
+func (a *adapter) Store(otpType domain.OTPType, requestID uuid.UUID, phone, code string, channel domain.OTPChannel) error {
 	otpSendRateLimitStr, err := a.rds.Get(string(otpType) + ":" + phone).Result()
 	if err != nil && !errors.Is(err, redis.Nil) {
 		a.logger.WithError(err).Error("Error while trying to get number of attempts!")
@@ -119,6 +120,7 @@ func (a *adapter) Store(otpType domain.OTPType, requestID uuid.UUID, phone, code
 
 	var checkRateLimit otpCheckRateLimit
 	checkRateLimit.Code = code
+	checkRateLimit.Channel = channel
 	b2, _ := json.Marshal(checkRateLimit)
 
 	if err := a.rds.SetNX(string(otpType)+":"+requestID.String(), b2, 5*time.Minute).Err(); err != nil {
@@ -185,6 +187,36 @@ func (a *adapter) Verify(otpType domain.OTPType, requestID uuid.UUID, phone, cod
 	return nil
 }
 
+func (a *adapter) StoreSessionsRevocation(token string, userID int, ttl time.Duration) error {
+	if err := a.rds.Set("revocation:"+token, userID, ttl).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to store a revocation token!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+// LoadSessionsRevocation returns the user of the token, a token is used once.
+func (a *adapter) LoadSessionsRevocation(token string) (int, error) {
+	userID, err := a.rds.Get("revocation:" + token).Int()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			a.logger.WithError(err).Error("Revocation token doesn't exist or it's already expired!")
+			return 0, domain.ErrNonexistentOrExpiredToken
+		}
+
+		a.logger.WithError(err).Error("Error while trying to get a revocation token!")
+		return 0, domain.ErrInternalOTPStore
+	}
+
+	if err := a.rds.Del("revocation:" + token).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to delete a used token!")
+		return 0, domain.ErrInternalOTPStore
+	}
+
+	return userID, nil
+}
+
 func (a *adapter) StoreEmail(token, emailAddress string) error {
 	if err := a.rds.Del("email:" + token).Err(); err != nil {
 		a.logger.WithError(err).Error("Error while trying to delete an old email token!")
@@ -218,3 +250,321 @@ func (a *adapter) GetEmail(token string) (string, error) {
 
 	return storedEmail, nil
 }
+
+type totpChallenge struct {
+	UserID int `json:"user_id"`
+}
+
+func (a *adapter) StoreTOTPChallenge(requestID uuid.UUID, userID int) error {
+	b, _ := json.Marshal(totpChallenge{UserID: userID})
+
+	if err := a.rds.Set("totp:"+requestID.String(), b, 5*time.Minute).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to store a TOTP challenge!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+// LoadTOTPChallenge counts every load as an attempt to check a code. The counter is a separate key
+// incremented atomically, so concurrent requests can't exceed the limit.
+func (a *adapter) LoadTOTPChallenge(requestID uuid.UUID) (int, error) {
+	key := "totp:" + requestID.String()
+
+	pipe := a.rds.TxPipeline()
+	get := pipe.Get(key)
+	incr := pipe.Incr(key + ":attempts")
+	pipe.Expire(key+":attempts", 5*time.Minute)
+	if _, err := pipe.Exec(); err != nil && !errors.Is(err, redis.Nil) {
+		a.logger.WithError(err).Error("Error while trying to get a TOTP challenge!")
+		return 0, domain.ErrInternalOTPStore
+	}
+
+	challengeStr, err := get.Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			a.logger.WithError(err).Error("There was no TOTP challenge or it's already expired!")
+			return 0, domain.ErrNonexistentOrExpiredCode
+		}
+
+		a.logger.WithError(err).Error("Error while trying to get a TOTP challenge!")
+		return 0, domain.ErrInternalOTPStore
+	}
+
+	if incr.Val() > 5 {
+		if err := a.DeleteTOTPChallenge(requestID); err != nil {
+			return 0, err
+		}
+
+		return 0, domain.ErrOTPAttemptsExceeded
+	}
+
+	var challenge totpChallenge
+	_ = json.Unmarshal([]byte(challengeStr), &challenge)
+
+	return challenge.UserID, nil
+}
+
+func (a *adapter) DeleteTOTPChallenge(requestID uuid.UUID) error {
+	key := "totp:" + requestID.String()
+	if err := a.rds.Del(key, key+":attempts").Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to delete a TOTP challenge!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+// CountTOTPAttempt counts a check of a code of the user and fails once the limit is reached.
+// Every attempt extends the lockout, so it lasts while codes are being guessed.
+func (a *adapter) CountTOTPAttempt(userID int) error {
+	key := "totp_attempts:" + strconv.Itoa(userID)
+
+	pipe := a.rds.TxPipeline()
+	incr := pipe.Incr(key)
+	pipe.Expire(key, 15*time.Minute)
+	if _, err := pipe.Exec(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to count a TOTP attempt!")
+		return domain.ErrInternalOTPStore
+	}
+
+	if incr.Val() > 5 {
+		return domain.ErrTOTPAttemptsExceeded
+	}
+
+	return nil
+}
+
+func (a *adapter) ResetTOTPAttempts(userID int) error {
+	if err := a.rds.Del("totp_attempts:" + strconv.Itoa(userID)).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to reset TOTP attempts!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+type webAuthnSession struct {
+	Challenge []byte `json:"challenge"`
+	UserID    int    `json:"user_id"`
+}
+
+func (a *adapter) StoreWebAuthnSession(requestID uuid.UUID, session *domain.WebAuthnSession, ttl time.Duration) error {
+	b, _ := json.Marshal(webAuthnSession{
+		Challenge: session.Challenge,
+		UserID:    session.UserID,
+	})
+
+	if err := a.rds.Set("webauthn:"+requestID.String(), b, ttl).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to store a WebAuthn session!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+// LoadWebAuthnSession deletes the session, every challenge may be answered only once.
+func (a *adapter) LoadWebAuthnSession(requestID uuid.UUID) (*domain.WebAuthnSession, error) {
+	sessionStr, err := a.rds.Get("webauthn:" + requestID.String()).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			a.logger.WithError(err).Error("There was no WebAuthn session or it's already expired!")
+			return nil, domain.ErrNonexistentOrExpiredCode
+		}
+
+		a.logger.WithError(err).Error("Error while trying to get a WebAuthn session!")
+		return nil, domain.ErrInternalOTPStore
+	}
+
+	if err := a.rds.Del("webauthn:" + requestID.String()).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to delete a WebAuthn session!")
+		return nil, domain.ErrInternalOTPStore
+	}
+
+	var session webAuthnSession
+	if err := json.Unmarshal([]byte(sessionStr), &session); err != nil {
+		a.logger.WithError(err).Error("Error while unmarshalling a WebAuthn session!")
+		return nil, domain.ErrInternalOTPStore
+	}
+
+	return &domain.WebAuthnSession{
+		Challenge: session.Challenge,
+		UserID:    session.UserID,
+	}, nil
+}
+
+type phoneChange struct {
+	UserID       int    `json:"user_id"`
+	OldPhone     string `json:"old_phone"`
+	NewPhone     string `json:"new_phone"`
+	OldChannel   string `json:"old_channel"`
+	OldConfirmed bool   `json:"old_confirmed"`
+}
+
+func (a *adapter) StorePhoneChange(requestID uuid.UUID, c *domain.PhoneChange, ttl time.Duration) error {
+	b, _ := json.Marshal(phoneChange{
+		UserID:       c.UserID,
+		OldPhone:     c.OldPhone,
+		NewPhone:     c.NewPhone,
+		OldChannel:   string(c.OldChannel),
+		OldConfirmed: c.OldConfirmed,
+	})
+
+	if err := a.rds.Set("phone_change:"+requestID.String(), b, ttl).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to store a phone change!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+func (a *adapter) LoadPhoneChange(requestID uuid.UUID) (*domain.PhoneChange, error) {
+	changeStr, err := a.rds.Get("phone_change:" + requestID.String()).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			a.logger.WithError(err).Error("There was no phone change or it's already expired!")
+			return nil, domain.ErrNonexistentOrExpiredCode
+		}
+
+		a.logger.WithError(err).Error("Error while trying to get a phone change!")
+		return nil, domain.ErrInternalOTPStore
+	}
+
+	var c phoneChange
+	if err := json.Unmarshal([]byte(changeStr), &c); err != nil {
+		a.logger.WithError(err).Error("Error while unmarshalling a phone change!")
+		return nil, domain.ErrInternalOTPStore
+	}
+
+	return &domain.PhoneChange{
+		UserID:       c.UserID,
+		OldPhone:     c.OldPhone,
+		NewPhone:     c.NewPhone,
+		OldChannel:   domain.OTPChannel(c.OldChannel),
+		OldConfirmed: c.OldConfirmed,
+	}, nil
+}
+
+func (a *adapter) DeletePhoneChange(requestID uuid.UUID) error {
+	if err := a.rds.Del("phone_change:" + requestID.String()).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to delete a phone change!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+type authorizationCode struct {
+	ClientID      string   `json:"client_id"`
+	UserID        int      `json:"user_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	Nonce         string   `json:"nonce"`
+	CodeChallenge string   `json:"code_challenge"`
+}
+
+func (a *adapter) StoreAuthorizationCode(code string, c *domain.AuthorizationCode, ttl time.Duration) error {
+	b, _ := json.Marshal(authorizationCode{
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		RedirectURI:   c.RedirectURI,
+		Scopes:        c.Scopes,
+		Nonce:         c.Nonce,
+		CodeChallenge: c.CodeChallenge,
+	})
+
+	if err := a.rds.Set("oidc:"+code, b, ttl).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to store an authorization code!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+// LoadAuthorizationCode deletes the code, it may be exchanged only once.
+func (a *adapter) LoadAuthorizationCode(code string) (*domain.AuthorizationCode, error) {
+	// Reading and deleting in a transaction prevents concurrent exchanges
+	var get *redis.StringCmd
+	if _, err := a.rds.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get("oidc:" + code)
+		pipe.Del("oidc:" + code)
+		return nil
+	}); err != nil {
+		if errors.Is(err, redis.Nil) {
+			a.logger.WithError(err).Info("There was no authorization code or it's already expired!")
+			return nil, domain.ErrOAuthInvalidGrant
+		}
+
+		a.logger.WithError(err).Error("Error while trying to get an authorization code!")
+		return nil, domain.ErrInternalOTPStore
+	}
+
+	codeStr := get.Val()
+
+	var c authorizationCode
+	if err := json.Unmarshal([]byte(codeStr), &c); err != nil {
+		a.logger.WithError(err).Error("Error while unmarshalling an authorization code!")
+		return nil, domain.ErrInternalOTPStore
+	}
+
+	return &domain.AuthorizationCode{
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		RedirectURI:   c.RedirectURI,
+		Scopes:        c.Scopes,
+		Nonce:         c.Nonce,
+		CodeChallenge: c.CodeChallenge,
+	}, nil
+}
+
+// RevokeFamilyAccessTokens denies tokens issued to every rotation of a session.
+func (a *adapter) RevokeFamilyAccessTokens(familyID uuid.UUID, ttl time.Duration) error {
+	if err := a.rds.Set("denylist:family:"+familyID.String(), 1, ttl).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to revoke access tokens of a session family!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+// RevokeUserAccessTokens denies tokens of the user issued before the time. Milliseconds are compared,
+// so a token of a login right after the revocation isn't denied.
+func (a *adapter) RevokeUserAccessTokens(userID int, issuedBefore time.Time, ttl time.Duration) error {
+	if err := a.rds.Set("denylist:user:"+strconv.Itoa(userID), unixMilli(issuedBefore), ttl).Err(); err != nil {
+		a.logger.WithError(err).Error("Error while trying to revoke access tokens of a user!")
+		return domain.ErrInternalOTPStore
+	}
+
+	return nil
+}
+
+func (a *adapter) IsAccessTokenRevoked(c *domain.AccessTokenClaims) (bool, error) {
+	values, err := a.rds.MGet(
+		"denylist:family:"+c.FamilyID.String(),
+		"denylist:user:"+strconv.Itoa(c.UserID),
+	).Result()
+	if err != nil {
+		a.logger.WithError(err).Error("Error while trying to check the access token denylist!")
+		return false, domain.ErrInternalOTPStore
+	}
+
+	if c.FamilyID != uuid.Nil && values[0] != nil {
+		return true, nil
+	}
+
+	if revokedAt, ok := values[1].(string); ok {
+		issuedBefore, err := strconv.ParseInt(revokedAt, 10, 64)
+		if err != nil {
+			a.logger.WithError(err).Error("Error while parsing a revocation time!")
+			return false, domain.ErrInternalOTPStore
+		}
+
+		return unixMilli(c.IssuedAt) < issuedBefore, nil
+	}
+
+	return false, nil
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
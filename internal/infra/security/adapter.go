@@ -1,53 +1,78 @@
 package security
 
 import (
+	"crypto/cipher"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"math/big"
-	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"trainee-assignment-backend/internal/domain"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/go-chi/jwtauth"
+	"github.com/google/uuid"
 
 	"github.com/sirupsen/logrus"
 )
 
+const issuer = "woman-bank"
+
+// accessTokenClaims of a token issued to an OAuth client have the audience and scopes,
+// first-party tokens carry roles of the user, the refresh session ID and its family.
+// The private issue time in milliseconds tells a revocation apart from a login of the same second,
+// iat keeps whole seconds for standard libraries.
+type accessTokenClaims struct {
+	jwt.StandardClaims
+	IssuedAtMilli int64    `json:"iat_ms,omitempty"`
+	Scope         string   `json:"scope,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	SessionID     int      `json:"sid,omitempty"`
+	FamilyID      string   `json:"fid,omitempty"`
+	AuthTime      int64    `json:"auth_time,omitempty"`
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
 type adapter struct {
-	logger  *logrus.Logger
-	config  *Config
-	jwtAuth *jwtauth.JWTAuth
+	logger *logrus.Logger
+	config *Config
+	db     domain.Database
+
+	// JWT signing keys, stored encrypted by the key-encryption key
+	kek  cipher.AEAD
+	mu   sync.RWMutex
+	ring *keyRing
 }
 
-func NewAdapter(logger *logrus.Logger, config *Config) (domain.Security, error) {
+func NewAdapter(logger *logrus.Logger, config *Config, db domain.Database) (domain.Security, error) {
 	a := &adapter{
 		logger: logger,
 		config: config,
+		db:     db,
 	}
 
-	// Read JWT signing key
-	fileWithAccessToken, err := os.Open(a.config.JWTPrivateKey)
-	if err != nil {
-		a.logger.WithError(err).Error("Error while opening file!")
-		return nil, err
+	if a.config.JWTKeyOverlap <= 0 || a.config.JWTKeyRotation <= a.config.JWTKeyOverlap {
+		return nil, errors.New("JWT key rotation must be longer than the overlap")
 	}
 
-	//noinspection ALL
-	defer fileWithAccessToken.Close()
-
-	bytes, err := ioutil.ReadAll(fileWithAccessToken)
+	kek, err := newKeyEncryption(a.config)
 	if err != nil {
-		a.logger.WithError(err).Error("Error while reading file!")
 		return nil, err
 	}
+	a.kek = kek
 
-	jwtAuth := jwtauth.New(jwt.SigningMethodHS256.Name, bytes, nil)
-	a.jwtAuth = jwtAuth
+	// Load or create signing keys
+	if _, err := a.getKeyRing(); err != nil {
+		a.logger.WithError(err).Error("Error while loading JWT signing keys!")
+		return nil, err
+	}
 
 	return a, nil
 }
@@ -75,21 +100,138 @@ func (a *adapter) GetRandomToken() (string, error) {
 	return hex.EncodeToString(randomness), nil
 }
 
-func (a *adapter) GetAccessToken(userID int, duration time.Duration) (string, error) {
+func (a *adapter) GetAccessToken(
+	userID, sessionID int,
+	familyID uuid.UUID,
+	roles []string,
+	authTime time.Time,
+	duration time.Duration,
+) (string, error) {
 	now := time.Now().In(time.UTC)
-	iss := now.Unix()
-	exp := now.Add(duration).Unix()
-
-	_, accessToken, err := a.jwtAuth.Encode(jwt.StandardClaims{
-		ExpiresAt: exp,
-		IssuedAt:  iss,
-		Issuer:    "woman-bank",
-		Subject:   strconv.Itoa(userID),
-	})
+
+	claims := &accessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			ExpiresAt: now.Add(duration).Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    issuer,
+			Subject:   strconv.Itoa(userID),
+		},
+		IssuedAtMilli: unixMilli(now),
+		Roles:         roles,
+		SessionID:     sessionID,
+		FamilyID:      familyID.String(),
+	}
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
+
+	return a.sign(claims)
+}
+
+// sign signs claims with the active key.
+func (a *adapter) sign(claims jwt.Claims) (string, error) {
+	ring, err := a.getKeyRing()
 	if err != nil {
-		a.logger.WithError(err).Error("Error while encoding access token!")
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(ring.signing.method, claims)
+	token.Header["kid"] = ring.signing.kid
+
+	signed, err := token.SignedString(ring.signing.privateKey)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while signing a token!")
 		return "", domain.ErrInternalSecurity
 	}
 
-	return accessToken, nil
+	return signed, nil
+}
+
+func (a *adapter) ParseAccessToken(accessToken string) (*domain.AccessTokenClaims, error) {
+	ring, err := a.getKeyRing()
+	if err != nil {
+		return nil, err
+	}
+
+	var claims accessTokenClaims
+	if _, err := jwt.ParseWithClaims(accessToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		k := ring.key(kid)
+		if k == nil {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+
+		if token.Method != k.method {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return k.privateKey.Public(), nil
+	}); err != nil {
+		a.logger.WithError(err).Info("Invalid access token!")
+		return nil, domain.ErrUnauthorized
+	}
+
+	if claims.ExpiresAt == 0 || !claims.VerifyIssuer(issuer, true) {
+		a.logger.Info("Access token without expiration or with a wrong issuer!")
+		return nil, domain.ErrUnauthorized
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		a.logger.WithError(err).Info("Invalid subject of an access token!")
+		return nil, domain.ErrUnauthorized
+	}
+
+	var scopes []string
+	if claims.Scope != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+
+	var familyID uuid.UUID
+	if claims.FamilyID != "" {
+		if familyID, err = uuid.Parse(claims.FamilyID); err != nil {
+			a.logger.WithError(err).Info("Invalid session family of an access token!")
+			return nil, domain.ErrUnauthorized
+		}
+	}
+
+	issuedAt := time.Unix(claims.IssuedAt, 0).In(time.UTC)
+	if claims.IssuedAtMilli != 0 {
+		issuedAt = time.Unix(0, claims.IssuedAtMilli*int64(time.Millisecond)).In(time.UTC)
+	}
+
+	var authTime time.Time
+	if claims.AuthTime != 0 {
+		authTime = time.Unix(claims.AuthTime, 0).In(time.UTC)
+	}
+
+	return &domain.AccessTokenClaims{
+		ID:        claims.Id,
+		UserID:    userID,
+		SessionID: claims.SessionID,
+		FamilyID:  familyID,
+		ClientID:  claims.Audience,
+		Scopes:    scopes,
+		Roles:     claims.Roles,
+		AuthTime:  authTime,
+		IssuedAt:  issuedAt,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).In(time.UTC),
+	}, nil
+}
+
+// GetJWKS returns the public keys to verify access tokens, including the ones not used yet.
+func (a *adapter) GetJWKS() ([]*domain.JSONWebKey, error) {
+	ring, err := a.getKeyRing()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*domain.JSONWebKey, 0, len(ring.keys))
+	for _, k := range ring.keys {
+		keys = append(keys, k.jwk())
+	}
+
+	return keys, nil
 }
@@ -1,5 +1,14 @@
 package security
 
+import "time"
+
 type Config struct {
-	JWTPrivateKey string `long:"jwt-private-key" env:"JWT_PRIVATE_KEY" description:"Path to JWT private key" required:"yes"`
+	JWTAlgorithm         string        `long:"jwt-algorithm" env:"JWT_ALGORITHM" description:"JWT signing algorithm" choice:"RS256" choice:"ES256" choice:"EdDSA" default:"ES256"`
+	JWTKeyRotation       time.Duration `long:"jwt-key-rotation" env:"JWT_KEY_ROTATION" description:"Time a JWT signing key is used before rotation" default:"720h"`
+	JWTKeyOverlap        time.Duration `long:"jwt-key-overlap" env:"JWT_KEY_OVERLAP" description:"Time a JWT key is published before use and kept after retirement" default:"24h"`
+	JWTKeyRefresh        time.Duration `long:"jwt-key-refresh" env:"JWT_KEY_REFRESH" description:"Interval of reloading JWT signing keys" default:"1m"`
+	KeyEncryptionKey     string        `long:"key-encryption-key" env:"KEY_ENCRYPTION_KEY" description:"Base64 encoded 32-byte key encrypting JWT signing keys at rest"`
+	KeyEncryptionKeyFile string        `long:"key-encryption-key-file" env:"KEY_ENCRYPTION_KEY_FILE" description:"Path to a file with the key encrypting JWT signing keys, used when the key isn't set"`
+	OIDCIssuer           string        `long:"oidc-issuer" env:"OIDC_ISSUER" description:"Issuer URL of the OpenID Connect provider" required:"yes"`
+	TOTPIssuer           string        `long:"totp-issuer" env:"TOTP_ISSUER" description:"Issuer shown by authenticator apps" default:"Woman Club"`
 }
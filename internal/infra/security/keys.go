@@ -0,0 +1,298 @@
+package security
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type signingKey struct {
+	kid         string
+	method      jwt.SigningMethod
+	privateKey  crypto.Signer
+	activatesAt time.Time
+}
+
+// keyRing holds the published keys in order of activation and the one to sign with.
+type keyRing struct {
+	keys     []*signingKey
+	signing  *signingKey
+	loadedAt time.Time
+}
+
+func (r *keyRing) key(kid string) *signingKey {
+	for _, k := range r.keys {
+		if k.kid == kid {
+			return k
+		}
+	}
+
+	return nil
+}
+
+// getKeyRing reloads keys once in the refresh interval. The loaded keys are used while the database is unavailable.
+func (a *adapter) getKeyRing() (*keyRing, error) {
+	a.mu.RLock()
+	ring := a.ring
+	a.mu.RUnlock()
+
+	if ring != nil && time.Since(ring.loadedAt) < a.config.JWTKeyRefresh {
+		return ring, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.ring != nil && time.Since(a.ring.loadedAt) < a.config.JWTKeyRefresh {
+		return a.ring, nil
+	}
+
+	ring, err := a.loadKeyRing()
+	if err != nil {
+		if a.ring != nil {
+			a.logger.WithError(err).Warn("Error while reloading signing keys, the loaded ones are kept!")
+			return a.ring, nil
+		}
+
+		return nil, err
+	}
+
+	a.ring = ring
+	return ring, nil
+}
+
+// loadKeyRing rotates the newest key when it is due. A new key is published for the overlap window
+// before it is used to sign, and the retired ones are kept for the overlap window after that.
+func (a *adapter) loadKeyRing() (*keyRing, error) {
+	keys, err := a.db.GetSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(time.UTC)
+
+	var newest *domain.SigningKey
+	if len(keys) > 0 {
+		newest = keys[len(keys)-1]
+	}
+
+	if newest == nil ||
+		newest.Algorithm != a.config.JWTAlgorithm ||
+		!now.Before(newest.ActivatesAt.Add(a.config.JWTKeyRotation-a.config.JWTKeyOverlap)) {
+		// Nobody could have cached a key yet
+		activatesAt := now
+		previousKID := ""
+		if newest != nil {
+			activatesAt = now.Add(a.config.JWTKeyOverlap)
+			previousKID = newest.KID
+		}
+
+		k, err := a.generateSigningKey(activatesAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := a.db.RotateSigningKey(k, previousKID, activatesAt.Add(a.config.JWTKeyOverlap)); err != nil {
+			return nil, err
+		}
+
+		a.logger.WithField("kid", k.KID).Info("JWT signing key is rotated.")
+
+		keys, err = a.db.GetSigningKeys()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ring := &keyRing{
+		keys:     make([]*signingKey, 0, len(keys)),
+		loadedAt: time.Now(),
+	}
+
+	for _, k := range keys {
+		parsed, err := a.parseSigningKey(k)
+		if err != nil {
+			a.logger.WithError(err).WithField("kid", k.KID).Error("Error while parsing a signing key!")
+			continue
+		}
+
+		ring.keys = append(ring.keys, parsed)
+		if !parsed.activatesAt.After(now) {
+			ring.signing = parsed
+		}
+	}
+
+	if ring.signing == nil {
+		a.logger.Error("There is no active JWT signing key!")
+		return nil, domain.ErrInternalSecurity
+	}
+
+	return ring, nil
+}
+
+func (a *adapter) generateSigningKey(activatesAt time.Time) (*domain.SigningKey, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+
+	switch a.config.JWTAlgorithm {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256.Alg():
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case SigningMethodEdDSA.Alg():
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported algorithm %s", a.config.JWTAlgorithm)
+	}
+	if err != nil {
+		a.logger.WithError(err).Error("Error while generating a signing key!")
+		return nil, domain.ErrInternalSecurity
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while marshalling a signing key!")
+		return nil, domain.ErrInternalSecurity
+	}
+
+	kid := make([]byte, 16)
+	if _, err := rand.Read(kid); err != nil {
+		a.logger.WithError(err).Error("Error while generating randomness!")
+		return nil, domain.ErrInternalSecurity
+	}
+
+	k := &domain.SigningKey{
+		KID:         base64.RawURLEncoding.EncodeToString(kid),
+		Algorithm:   a.config.JWTAlgorithm,
+		ActivatesAt: activatesAt,
+	}
+
+	nonce := make([]byte, a.kek.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		a.logger.WithError(err).Error("Error while generating randomness!")
+		return nil, domain.ErrInternalSecurity
+	}
+
+	// The key ID is authenticated, so an encrypted key can't be swapped into another row
+	k.PrivateKey = a.kek.Seal(nonce, nonce, der, []byte(k.KID))
+
+	return k, nil
+}
+
+func (a *adapter) parseSigningKey(k *domain.SigningKey) (*signingKey, error) {
+	method := jwt.GetSigningMethod(k.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported algorithm %s", k.Algorithm)
+	}
+
+	nonceSize := a.kek.NonceSize()
+	if len(k.PrivateKey) < nonceSize {
+		return nil, errors.New("encrypted key is too short")
+	}
+
+	der, err := a.kek.Open(nil, k.PrivateKey[:nonceSize], k.PrivateKey[nonceSize:], []byte(k.KID))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting the key: %w", err)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	switch privateKey.(type) {
+	case *rsa.PrivateKey:
+		ok = method == jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		ok = method == jwt.SigningMethodES256
+	case ed25519.PrivateKey:
+		ok = method == SigningMethodEdDSA
+	}
+	if !ok {
+		return nil, fmt.Errorf("key type %T doesn't match algorithm %s", privateKey, k.Algorithm)
+	}
+
+	return &signingKey{
+		kid:         k.KID,
+		method:      method,
+		privateKey:  privateKey.(crypto.Signer),
+		activatesAt: k.ActivatesAt,
+	}, nil
+}
+
+// newKeyEncryption creates AES-256-GCM of the key-encryption key from the config or the file.
+func newKeyEncryption(config *Config) (cipher.AEAD, error) {
+	encoded := config.KeyEncryptionKey
+	if encoded == "" {
+		if config.KeyEncryptionKeyFile == "" {
+			return nil, errors.New("key-encryption key of JWT signing keys is not set")
+		}
+
+		b, err := ioutil.ReadFile(config.KeyEncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading the key-encryption key: %w", err)
+		}
+		encoded = strings.TrimSpace(string(b))
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding the key-encryption key: %w", err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key-encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func (k *signingKey) jwk() *domain.JSONWebKey {
+	jwk := &domain.JSONWebKey{
+		KeyID:     k.kid,
+		Use:       "sig",
+		Algorithm: k.method.Alg(),
+	}
+
+	switch publicKey := k.privateKey.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.KeyType = "EC"
+		jwk.Curve = "P-256"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+
+	return jwk
+}
@@ -0,0 +1,75 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+type idTokenClaims struct {
+	jwt.StandardClaims
+	Nonce string `json:"nonce,omitempty"`
+}
+
+func (a *adapter) GetOIDCIssuer() string {
+	return a.config.OIDCIssuer
+}
+
+// GetClientAccessToken issues an access token to an OAuth client on behalf of the user.
+func (a *adapter) GetClientAccessToken(userID int, clientID string, scopes []string, duration time.Duration) (string, error) {
+	now := time.Now().In(time.UTC)
+
+	return a.sign(&accessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			Audience:  clientID,
+			ExpiresAt: now.Add(duration).Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    issuer,
+			Subject:   strconv.Itoa(userID),
+		},
+		IssuedAtMilli: unixMilli(now),
+		Scope:         strings.Join(scopes, " "),
+	})
+}
+
+func (a *adapter) GetIDToken(c *domain.IDTokenClaims, duration time.Duration) (string, error) {
+	now := time.Now().In(time.UTC)
+
+	return a.sign(&idTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  c.ClientID,
+			ExpiresAt: now.Add(duration).Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    a.config.OIDCIssuer,
+			Subject:   strconv.Itoa(c.UserID),
+		},
+		Nonce: c.Nonce,
+	})
+}
+
+// HashClientSecret hashes a generated high-entropy client secret.
+func (a *adapter) HashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyCodeChallenge checks a PKCE (RFC 7636) verifier against an S256 challenge.
+func (a *adapter) VerifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,103 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// Accepted clock drift in periods
+	totpSkew = 1
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (a *adapter) GetTOTPSecret() (string, error) {
+	randomness := make([]byte, 20)
+	if _, err := rand.Read(randomness); err != nil {
+		a.logger.WithError(err).Error("Error while generating randomness!")
+		return "", domain.ErrInternalSecurity
+	}
+
+	return base32NoPadding.EncodeToString(randomness), nil
+}
+
+func (a *adapter) GetTOTPProvisioningURI(secret, account string) string {
+	label := url.PathEscape(a.config.TOTPIssuer + ":" + account)
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", a.config.TOTPIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	// Some authenticator apps do not decode "+" as a space
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// ValidateTOTP returns the time step of a valid code, so the code isn't accepted twice.
+func (a *adapter) ValidateTOTP(secret, code string) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		a.logger.WithError(err).Error("Error while decoding a TOTP secret!")
+		return 0, false
+	}
+
+	counter := time.Now().Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		step := counter + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(step))), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+func (a *adapter) GetRecoveryCode() (string, error) {
+	randomness := make([]byte, 10)
+	if _, err := rand.Read(randomness); err != nil {
+		a.logger.WithError(err).Error("Error while generating randomness!")
+		return "", domain.ErrInternalSecurity
+	}
+
+	code := base32NoPadding.EncodeToString(randomness)
+
+	return code[:8] + "-" + code[8:], nil
+}
+
+func (a *adapter) HashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// hotp implements RFC 4226 with SHA-1 and 6 digits.
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
@@ -1,26 +1,60 @@
 package sms
 
 import (
+	"fmt"
+	"sort"
 	"trainee-assignment-backend/internal/domain"
 
 	"github.com/sirupsen/logrus"
 )
 
-type adapter struct {
-	logger *logrus.Logger
-	config *Config
+// Factory creates an SMS driver from the configuration.
+type Factory func(logger *logrus.Logger, config *Config) (domain.SMSSender, error)
+
+var drivers = make(map[string]Factory)
+
+// Register makes an SMS driver available by the name.
+func Register(name string, factory Factory) {
+	if _, ok := drivers[name]; ok {
+		panic("sms: driver " + name + " is already registered")
+	}
+
+	drivers[name] = factory
 }
 
-func NewAdapter(logger *logrus.Logger, config *Config) domain.SMSSender {
-	return &adapter{
-		logger: logger,
-		config: config,
+// Drivers returns sorted names of registered drivers.
+func Drivers() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+
+	return names
 }
 
-func (a *adapter) SendSMS(phone, text string) error {
-	// temp info log with sms text
-	a.logger.Info("phone:" + phone + ", text:" + text)
-	// add implementation
-	return nil
+// Creating a new SMS adapter with the primary driver and ordered fallbacks.
+func NewAdapter(logger *logrus.Logger, config *Config) (domain.SMSSender, error) {
+	names := append([]string{config.Driver}, config.Fallbacks...)
+
+	providers := make([]*provider, 0, len(names))
+	for _, name := range names {
+		factory, ok := drivers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown sms driver %q, available: %v", name, Drivers())
+		}
+
+		sender, err := factory(logger, config)
+		if err != nil {
+			return nil, err
+		}
+
+		providers = append(providers, &provider{
+			name:    name,
+			sender:  sender,
+			breaker: newBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		})
+	}
+
+	return newFailover(logger, config, providers), nil
 }
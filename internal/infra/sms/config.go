@@ -1,5 +1,33 @@
 package sms
 
+import (
+	"time"
+	"trainee-assignment-backend/pkg/httpapi"
+)
+
 type Config struct {
+	Driver    string   `long:"driver" env:"DRIVER" description:"Primary SMS driver (log, http, smpp)" default:"log"`
+	Fallbacks []string `long:"fallback" env:"FALLBACKS" env-delim:"," description:"Fallback SMS drivers in order of use"`
+
+	Retries          int           `long:"retries" env:"RETRIES" description:"Retries per provider before falling back" default:"2"`
+	RetryBackoff     time.Duration `long:"retry-backoff" env:"RETRY_BACKOFF" description:"Initial backoff between retries" default:"200ms"`
+	RetryMaxBackoff  time.Duration `long:"retry-max-backoff" env:"RETRY_MAX_BACKOFF" description:"Maximum backoff between retries" default:"2s"`
+	BreakerThreshold int           `long:"breaker-threshold" env:"BREAKER_THRESHOLD" description:"Consecutive failures to open a provider circuit breaker" default:"5"`
+	BreakerCooldown  time.Duration `long:"breaker-cooldown" env:"BREAKER_COOLDOWN" description:"Time before an open circuit breaker lets a probe through" default:"1m"`
+
+	HTTP *httpapi.Config `group:"SMS HTTP driver args" namespace:"http" env-namespace:"HTTP"`
+	SMPP *SMPPConfig     `group:"SMS SMPP driver args" namespace:"smpp" env-namespace:"SMPP"`
+}
 
+type SMPPConfig struct {
+	Addr       string        `long:"addr" env:"ADDR" description:"SMSC address (host:port)"`
+	SystemID   string        `long:"system-id" env:"SYSTEM_ID" description:"ESME system id"`
+	Password   string        `long:"password" env:"PASSWORD" description:"ESME password"`
+	SystemType string        `long:"system-type" env:"SYSTEM_TYPE" description:"ESME system type"`
+	SourceAddr string        `long:"source-addr" env:"SOURCE_ADDR" description:"Sender name or number"`
+	SourceTON  uint8         `long:"source-ton" env:"SOURCE_TON" description:"Source address type of number" default:"5"`
+	SourceNPI  uint8         `long:"source-npi" env:"SOURCE_NPI" description:"Source address numbering plan indicator" default:"0"`
+	DestTON    uint8         `long:"dest-ton" env:"DEST_TON" description:"Destination address type of number" default:"1"`
+	DestNPI    uint8         `long:"dest-npi" env:"DEST_NPI" description:"Destination address numbering plan indicator" default:"1"`
+	Timeout    time.Duration `long:"timeout" env:"TIMEOUT" description:"Network timeout" default:"10s"`
 }
@@ -0,0 +1,164 @@
+package sms
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// failover tries providers in order, retrying each of them with a backoff.
+// A provider with an open circuit breaker is skipped without any attempt.
+type failover struct {
+	logger    *logrus.Logger
+	config    *Config
+	providers []*provider
+
+	// Replaced in tests not to wait for backoffs
+	sleep func(time.Duration)
+}
+
+type provider struct {
+	name    string
+	sender  domain.SMSSender
+	breaker *breaker
+}
+
+func newFailover(logger *logrus.Logger, config *Config, providers []*provider) domain.SMSSender {
+	return &failover{
+		logger:    logger,
+		config:    config,
+		providers: providers,
+		sleep:     time.Sleep,
+	}
+}
+
+func (f *failover) SendSMS(phone, text string) (*domain.MessageReceipt, error) {
+	for _, p := range f.providers {
+		if !p.breaker.Allow() {
+			f.logger.WithField("provider", p.name).Warn("SMS provider is skipped, its circuit breaker is open.")
+			continue
+		}
+
+		receipt, err := f.send(p, phone, text)
+		if err != nil {
+			f.logger.WithError(err).WithField("provider", p.name).Error("SMS provider has failed, trying the next one!")
+			continue
+		}
+
+		receipt.Provider = p.name
+
+		f.logger.WithFields(logrus.Fields{
+			"provider":   receipt.Provider,
+			"message_id": receipt.MessageID,
+		}).Info("SMS is delivered to the provider.")
+
+		return receipt, nil
+	}
+
+	return nil, domain.ErrSMSDeliveryFailed
+}
+
+func (f *failover) send(p *provider, phone, text string) (*domain.MessageReceipt, error) {
+	backoff := f.config.RetryBackoff
+
+	var err error
+	for attempt := 0; attempt <= f.config.Retries; attempt++ {
+		if attempt > 0 {
+			if !p.breaker.Allow() {
+				break
+			}
+
+			// Full jitter keeps simultaneous retries from hitting the provider at once
+			f.sleep(time.Duration(rand.Int63n(int64(backoff) + 1)))
+
+			backoff *= 2
+			if backoff > f.config.RetryMaxBackoff {
+				backoff = f.config.RetryMaxBackoff
+			}
+		}
+
+		var receipt *domain.MessageReceipt
+		receipt, err = p.sender.SendSMS(phone, text)
+		if err == nil {
+			p.breaker.Success()
+			return receipt, nil
+		}
+
+		p.breaker.Failure()
+	}
+
+	return nil, err
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker opens after a number of consecutive failures and lets a single
+// probe request through once the cooldown has passed.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+
+	// Replaced in tests to move past the cooldown
+	now func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (b *breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// The probe request is in flight
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+func (b *breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,335 @@
+package sms
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+var errStubSender = errors.New("provider is down")
+
+// stubSender fails its first failures calls.
+type stubSender struct {
+	failures int
+	calls    int
+}
+
+func (s *stubSender) SendSMS(_, _ string) (*domain.MessageReceipt, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return nil, errStubSender
+	}
+
+	return &domain.MessageReceipt{MessageID: "msg-1"}, nil
+}
+
+// fakeClock only moves when the code under test sleeps or the test advances it.
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestFailover(config *Config, clock *fakeClock, senders ...*stubSender) *failover {
+	providers := make([]*provider, 0, len(senders))
+	for i, sender := range senders {
+		b := newBreaker(config.BreakerThreshold, config.BreakerCooldown)
+		b.now = clock.Now
+
+		providers = append(providers, &provider{
+			name:    []string{"primary", "fallback", "last"}[i],
+			sender:  sender,
+			breaker: b,
+		})
+	}
+
+	f := newFailover(newTestLogger(), config, providers).(*failover)
+	f.sleep = clock.Sleep
+
+	return f
+}
+
+func TestFailoverOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []int
+
+		wantProvider string
+		wantCalls    []int
+		wantErr      error
+	}{
+		{
+			name:         "primary delivers",
+			failures:     []int{0, 0, 0},
+			wantProvider: "primary",
+			wantCalls:    []int{1, 0, 0},
+		},
+		{
+			name:         "primary fails",
+			failures:     []int{10, 0, 0},
+			wantProvider: "fallback",
+			wantCalls:    []int{2, 1, 0},
+		},
+		{
+			name:         "only the last one delivers",
+			failures:     []int{10, 10, 0},
+			wantProvider: "last",
+			wantCalls:    []int{2, 2, 1},
+		},
+		{
+			name:      "all fail",
+			failures:  []int{10, 10, 10},
+			wantCalls: []int{2, 2, 2},
+			wantErr:   domain.ErrSMSDeliveryFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			senders := make([]*stubSender, len(tt.failures))
+			for i, failures := range tt.failures {
+				senders[i] = &stubSender{failures: failures}
+			}
+
+			f := newTestFailover(&Config{
+				Retries:          1,
+				RetryBackoff:     time.Second,
+				RetryMaxBackoff:  time.Second,
+				BreakerThreshold: 5,
+				BreakerCooldown:  time.Minute,
+			}, newFakeClock(), senders...)
+
+			receipt, err := f.SendSMS("+79161234567", "code 1234")
+			if err != tt.wantErr {
+				t.Fatalf("SendSMS() error = %v, want %v", err, tt.wantErr)
+			}
+
+			for i, sender := range senders {
+				if sender.calls != tt.wantCalls[i] {
+					t.Errorf("calls of %s = %d, want %d", f.providers[i].name, sender.calls, tt.wantCalls[i])
+				}
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if receipt.Provider != tt.wantProvider || receipt.MessageID != "msg-1" {
+				t.Errorf("receipt = %+v, want message id msg-1 of %s", receipt, tt.wantProvider)
+			}
+		})
+	}
+}
+
+func TestFailoverSkipsOpenBreaker(t *testing.T) {
+	clock := newFakeClock()
+	primary, fallback := &stubSender{failures: 10}, &stubSender{}
+
+	f := newTestFailover(&Config{
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+	}, clock, primary, fallback)
+
+	for i := 0; i < 2; i++ {
+		receipt, err := f.SendSMS("+79161234567", "code 1234")
+		if err != nil {
+			t.Fatalf("SendSMS() error = %v", err)
+		}
+
+		if receipt.Provider != "fallback" {
+			t.Errorf("provider = %q, want fallback", receipt.Provider)
+		}
+	}
+
+	if primary.calls != 1 {
+		t.Errorf("calls of primary = %d, want 1 before the cooldown", primary.calls)
+	}
+
+	// The probe after the cooldown goes to the primary again
+	clock.Advance(time.Minute)
+	primary.failures = 0
+
+	receipt, err := f.SendSMS("+79161234567", "code 1234")
+	if err != nil {
+		t.Fatalf("SendSMS() error = %v", err)
+	}
+
+	if receipt.Provider != "primary" || primary.calls != 2 {
+		t.Errorf("provider = %q after %d calls, want primary after 2", receipt.Provider, primary.calls)
+	}
+}
+
+func TestFailoverRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		threshold int
+		failures  int
+
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "delivered after retries",
+			retries:   3,
+			threshold: 10,
+			failures:  2,
+			wantCalls: 3,
+		},
+		{
+			name:      "retry limit",
+			retries:   3,
+			threshold: 10,
+			failures:  10,
+			wantCalls: 4,
+			wantErr:   domain.ErrSMSDeliveryFailed,
+		},
+		{
+			name:      "no retries",
+			retries:   0,
+			threshold: 10,
+			failures:  10,
+			wantCalls: 1,
+			wantErr:   domain.ErrSMSDeliveryFailed,
+		},
+		{
+			name:      "breaker opens before the limit",
+			retries:   5,
+			threshold: 2,
+			failures:  10,
+			wantCalls: 2,
+			wantErr:   domain.ErrSMSDeliveryFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			sender := &stubSender{failures: tt.failures}
+
+			f := newTestFailover(&Config{
+				Retries:          tt.retries,
+				RetryBackoff:     100 * time.Millisecond,
+				RetryMaxBackoff:  300 * time.Millisecond,
+				BreakerThreshold: tt.threshold,
+				BreakerCooldown:  time.Minute,
+			}, clock, sender)
+
+			if _, err := f.SendSMS("+79161234567", "code 1234"); err != tt.wantErr {
+				t.Fatalf("SendSMS() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if sender.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", sender.calls, tt.wantCalls)
+			}
+
+			if len(clock.slept) != tt.wantCalls-1 {
+				t.Fatalf("sleeps = %d, want one before every retry", len(clock.slept))
+			}
+
+			// The jitter is within a backoff that doubles up to the maximum
+			backoff := 100 * time.Millisecond
+			for i, d := range clock.slept {
+				if d < 0 || d > backoff {
+					t.Errorf("sleep %d = %v, want within %v", i, d, backoff)
+				}
+
+				backoff *= 2
+				if backoff > 300*time.Millisecond {
+					backoff = 300 * time.Millisecond
+				}
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	clock := newFakeClock()
+
+	b := newBreaker(3, time.Minute)
+	b.now = clock.Now
+
+	checkState := func(name string, want breakerState) {
+		t.Helper()
+
+		if b.state != want {
+			t.Fatalf("%s: state = %d, want %d", name, b.state, want)
+		}
+	}
+
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	checkState("failures are reset by a success", breakerClosed)
+
+	if !b.Allow() {
+		t.Fatal("closed breaker doesn't allow")
+	}
+
+	b.Failure()
+	checkState("threshold", breakerOpen)
+
+	if b.Allow() {
+		t.Fatal("open breaker allows")
+	}
+
+	clock.Advance(time.Minute - time.Second)
+	if b.Allow() {
+		t.Fatal("open breaker allows before the cooldown")
+	}
+
+	clock.Advance(time.Second)
+	if !b.Allow() {
+		t.Fatal("open breaker doesn't allow a probe after the cooldown")
+	}
+	checkState("cooldown", breakerHalfOpen)
+
+	if b.Allow() {
+		t.Fatal("half-open breaker allows a second probe")
+	}
+
+	// A failed probe opens the breaker for another cooldown
+	b.Failure()
+	checkState("failed probe", breakerOpen)
+
+	if b.Allow() {
+		t.Fatal("breaker allows right after a failed probe")
+	}
+
+	clock.Advance(time.Minute)
+	if !b.Allow() {
+		t.Fatal("breaker doesn't allow a probe after another cooldown")
+	}
+
+	b.Success()
+	checkState("successful probe", breakerClosed)
+
+	for i := 0; i < 2; i++ {
+		if !b.Allow() {
+			t.Fatal("closed breaker doesn't allow")
+		}
+	}
+
+	// A single failure after the probe doesn't open it again
+	b.Failure()
+	checkState("failure after closing", breakerClosed)
+}
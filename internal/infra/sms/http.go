@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"fmt"
+	"strings"
+	"trainee-assignment-backend/internal/domain"
+	"trainee-assignment-backend/pkg/httpapi"
+	phonepkg "trainee-assignment-backend/pkg/phone"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	Register("http", newHTTPDriver)
+}
+
+// httpDriver sends messages through an HTTP API of a provider.
+type httpDriver struct {
+	logger *logrus.Logger
+	client *httpapi.Client
+}
+
+// httpTemplateData has the phone in the E.164 form, Digits is the one without the plus
+// and National is the significant national number.
+type httpTemplateData struct {
+	Phone    string
+	Digits   string
+	National string
+	Text     string
+}
+
+func newHTTPDriver(logger *logrus.Logger, config *Config) (domain.SMSSender, error) {
+	client, err := httpapi.NewClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("sms http driver: %w", err)
+	}
+
+	return &httpDriver{
+		logger: logger,
+		client: client,
+	}, nil
+}
+
+func (d *httpDriver) SendSMS(phone, text string) (*domain.MessageReceipt, error) {
+	data := httpTemplateData{
+		Phone:    phone,
+		Digits:   strings.TrimPrefix(phone, "+"),
+		National: strings.TrimPrefix(phone, "+"),
+		Text:     text,
+	}
+	if n, err := phonepkg.Parse(phone, ""); err == nil {
+		data.Digits, data.National = n.Digits(), n.National
+	}
+
+	messageID, err := d.client.Call(data)
+	if err != nil {
+		d.logger.WithError(err).Error("Error while sending an SMS through the provider API!")
+		return nil, domain.ErrInternalSMS
+	}
+
+	return &domain.MessageReceipt{
+		Provider:  "http",
+		MessageID: messageID,
+	}, nil
+}
@@ -0,0 +1,197 @@
+package sms
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"trainee-assignment-backend/internal/domain"
+	"trainee-assignment-backend/pkg/httpapi"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	return logger
+}
+
+func TestHTTPDriverSendSMS(t *testing.T) {
+	tests := []struct {
+		name   string
+		config httpapi.Config
+		phone  string
+		text   string
+
+		status   int
+		response string
+
+		wantMethod string
+		wantURI    string
+		wantBody   string
+		wantAuth   string
+		wantID     string
+		wantErr    error
+	}{
+		{
+			name: "json body",
+			config: httpapi.Config{
+				URL:             "{{.URL}}/send",
+				Method:          http.MethodPost,
+				ContentType:     "application/json",
+				RequestTemplate: `{"phone":{{json .Phone}},"text":{{json .Text}}}`,
+				MessageIDField:  "id",
+			},
+			phone:      "+79161234567",
+			text:       `Код "1234"`,
+			status:     http.StatusOK,
+			response:   `{"id":"m-1"}`,
+			wantMethod: http.MethodPost,
+			wantURI:    "/send",
+			wantBody:   `{"phone":"+79161234567","text":"Код \"1234\""}`,
+			wantID:     "m-1",
+		},
+		{
+			name: "query of a get request",
+			config: httpapi.Config{
+				URL:    "{{.URL}}/sms?to={{.Digits}}&national={{.National}}&msg={{urlquery .Text}}",
+				Method: http.MethodGet,
+			},
+			phone:      "+77011234567",
+			text:       "code 1234",
+			status:     http.StatusOK,
+			response:   `OK`,
+			wantMethod: http.MethodGet,
+			wantURI:    "/sms?to=77011234567&national=7011234567&msg=code+1234",
+		},
+		{
+			name: "auth header and nested fields",
+			config: httpapi.Config{
+				URL:             "{{.URL}}/send",
+				Method:          http.MethodPost,
+				ContentType:     "application/json",
+				AuthHeader:      "Authorization",
+				AuthValue:       "Bearer secret",
+				RequestTemplate: `{"to":{{json .Digits}}}`,
+				SuccessField:    "result.status",
+				SuccessValue:    "ok",
+				MessageIDField:  "result.messages.0.id",
+			},
+			phone:      "+442079460958",
+			status:     http.StatusAccepted,
+			response:   `{"result":{"status":"ok","messages":[{"id":42}]}}`,
+			wantMethod: http.MethodPost,
+			wantURI:    "/send",
+			wantBody:   `{"to":"442079460958"}`,
+			wantAuth:   "Bearer secret",
+			wantID:     "42",
+		},
+		{
+			name: "unsuccessful status",
+			config: httpapi.Config{
+				URL:    "{{.URL}}/send",
+				Method: http.MethodPost,
+			},
+			phone:      "+79161234567",
+			status:     http.StatusBadGateway,
+			response:   `upstream is down`,
+			wantMethod: http.MethodPost,
+			wantURI:    "/send",
+			wantErr:    domain.ErrInternalSMS,
+		},
+		{
+			name: "unsuccessful result",
+			config: httpapi.Config{
+				URL:          "{{.URL}}/send",
+				Method:       http.MethodPost,
+				SuccessField: "status",
+				SuccessValue: "ok",
+			},
+			phone:      "+79161234567",
+			status:     http.StatusOK,
+			response:   `{"status":"error","reason":"no balance"}`,
+			wantMethod: http.MethodPost,
+			wantURI:    "/send",
+			wantErr:    domain.ErrInternalSMS,
+		},
+		{
+			name: "missing message id",
+			config: httpapi.Config{
+				URL:            "{{.URL}}/send",
+				Method:         http.MethodPost,
+				MessageIDField: "id",
+			},
+			phone:      "+79161234567",
+			status:     http.StatusOK,
+			response:   `{}`,
+			wantMethod: http.MethodPost,
+			wantURI:    "/send",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				gotMethod string
+				gotURI    string
+				gotBody   string
+				gotAuth   string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+
+				gotMethod = r.Method
+				gotURI = r.RequestURI
+				gotBody = string(body)
+				gotAuth = r.Header.Get("Authorization")
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			// The server URL is known once it is started
+			config := tt.config
+			config.URL = server.URL + config.URL[len("{{.URL}}"):]
+
+			sender, err := newHTTPDriver(newTestLogger(), &Config{HTTP: &config})
+			if err != nil {
+				t.Fatalf("newHTTPDriver() error = %v", err)
+			}
+
+			receipt, err := sender.SendSMS(tt.phone, tt.text)
+			if err != tt.wantErr {
+				t.Fatalf("SendSMS() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if gotMethod != tt.wantMethod {
+				t.Errorf("method = %q, want %q", gotMethod, tt.wantMethod)
+			}
+			if gotURI != tt.wantURI {
+				t.Errorf("uri = %q, want %q", gotURI, tt.wantURI)
+			}
+			if gotBody != tt.wantBody {
+				t.Errorf("body = %q, want %q", gotBody, tt.wantBody)
+			}
+			if gotAuth != tt.wantAuth {
+				t.Errorf("auth = %q, want %q", gotAuth, tt.wantAuth)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if receipt.Provider != "http" || receipt.MessageID != tt.wantID {
+				t.Errorf("receipt = %+v, want message id %q", receipt, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestNewHTTPDriverWithoutURL(t *testing.T) {
+	if _, err := newHTTPDriver(newTestLogger(), &Config{HTTP: &httpapi.Config{}}); err == nil {
+		t.Fatal("newHTTPDriver() error = nil, want an error")
+	}
+}
@@ -0,0 +1,38 @@
+package sms
+
+import (
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	Register("log", newLogDriver)
+}
+
+// logDriver only writes messages to the log, it is intended for development.
+type logDriver struct {
+	logger *logrus.Logger
+}
+
+func newLogDriver(logger *logrus.Logger, _ *Config) (domain.SMSSender, error) {
+	return &logDriver{
+		logger: logger,
+	}, nil
+}
+
+func (d *logDriver) SendSMS(phone, text string) (*domain.MessageReceipt, error) {
+	messageID := uuid.New().String()
+
+	d.logger.WithFields(logrus.Fields{
+		"message_id": messageID,
+		"phone":      phone,
+		"text":       text,
+	}).Info("SMS is sent to the log.")
+
+	return &domain.MessageReceipt{
+		Provider:  "log",
+		MessageID: messageID,
+	}, nil
+}
@@ -0,0 +1,296 @@
+package sms
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+	"trainee-assignment-backend/pkg/phone"
+	"unicode/utf16"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	Register("smpp", newSMPPDriver)
+}
+
+// SMPP v3.4 command ids.
+const (
+	smppGenericNack         uint32 = 0x80000000
+	smppBindTransmitter     uint32 = 0x00000002
+	smppBindTransmitterResp uint32 = 0x80000002
+	smppSubmitSM            uint32 = 0x00000004
+	smppSubmitSMResp        uint32 = 0x80000004
+	smppUnbind              uint32 = 0x00000006
+	smppUnbindResp          uint32 = 0x80000006
+	smppEnquireLink         uint32 = 0x00000015
+	smppEnquireLinkResp     uint32 = 0x80000015
+)
+
+const (
+	smppInterfaceVersion  = 0x34
+	smppDataCodingDefault = 0x00
+	smppDataCodingUCS2    = 0x08
+	smppTagMessagePayload = 0x0424
+	smppMaxShortMessage   = 140
+	smppMaxPDULength      = 64 * 1024
+	smppTONNational       = 0x02
+)
+
+// smppDriver submits messages to an SMSC over SMPP v3.4.
+// Every message is sent within its own transmitter session.
+type smppDriver struct {
+	logger *logrus.Logger
+	config *SMPPConfig
+}
+
+func newSMPPDriver(logger *logrus.Logger, config *Config) (domain.SMSSender, error) {
+	if config.SMPP == nil || config.SMPP.Addr == "" {
+		return nil, errors.New("sms smpp driver: addr is required")
+	}
+
+	return &smppDriver{
+		logger: logger,
+		config: config.SMPP,
+	}, nil
+}
+
+func (d *smppDriver) SendSMS(phone, text string) (*domain.MessageReceipt, error) {
+	messageID, err := d.send(phone, text)
+	if err != nil {
+		d.logger.WithError(err).WithField("addr", d.config.Addr).Error("Error while sending an SMS over SMPP!")
+		return nil, domain.ErrInternalSMS
+	}
+
+	return &domain.MessageReceipt{
+		Provider:  "smpp",
+		MessageID: messageID,
+	}, nil
+}
+
+// address formats an E.164 phone for the destination type of number, international addresses go without the plus.
+func (d *smppDriver) address(e164 string) string {
+	n, err := phone.Parse(e164, "")
+	if err != nil {
+		return strings.TrimPrefix(e164, "+")
+	}
+
+	if d.config.DestTON == smppTONNational {
+		return n.National
+	}
+
+	return n.Digits()
+}
+
+func (d *smppDriver) send(phone, text string) (string, error) {
+	conn, err := net.DialTimeout("tcp", d.config.Addr, d.config.Timeout)
+	if err != nil {
+		return "", err
+	}
+
+	//noinspection ALL
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(d.config.Timeout)); err != nil {
+		return "", err
+	}
+
+	s := &smppSession{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+
+	var bind smppBody
+	bind.cString(d.config.SystemID)
+	bind.cString(d.config.Password)
+	bind.cString(d.config.SystemType)
+	bind.byte(smppInterfaceVersion)
+	bind.byte(0) // addr_ton
+	bind.byte(0) // addr_npi
+	bind.cString("")
+
+	if _, err := s.call(smppBindTransmitter, smppBindTransmitterResp, bind.Bytes()); err != nil {
+		return "", fmt.Errorf("bind: %w", err)
+	}
+
+	dataCoding, message := smppEncode(text)
+
+	var submit smppBody
+	submit.cString("") // service_type
+	submit.byte(d.config.SourceTON)
+	submit.byte(d.config.SourceNPI)
+	submit.cString(d.config.SourceAddr)
+	submit.byte(d.config.DestTON)
+	submit.byte(d.config.DestNPI)
+	submit.cString(d.address(phone))
+	submit.byte(0)     // esm_class
+	submit.byte(0)     // protocol_id
+	submit.byte(0)     // priority_flag
+	submit.cString("") // schedule_delivery_time
+	submit.cString("") // validity_period
+	submit.byte(0)     // registered_delivery
+	submit.byte(0)     // replace_if_present_flag
+	submit.byte(dataCoding)
+	submit.byte(0) // sm_default_msg_id
+	if len(message) <= smppMaxShortMessage {
+		submit.byte(uint8(len(message)))
+		submit.Write(message)
+	} else {
+		submit.byte(0)
+		submit.tlv(smppTagMessagePayload, message)
+	}
+
+	resp, err := s.call(smppSubmitSM, smppSubmitSMResp, submit.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("submit_sm: %w", err)
+	}
+
+	// submit_sm_resp body consists of the message_id C-string only
+	messageID := string(bytes.TrimRight(resp.body, "\x00"))
+
+	if _, err := s.call(smppUnbind, smppUnbindResp, nil); err != nil {
+		// The message is already accepted, so it is not a reason to fail.
+		d.logger.WithError(err).Warn("Error while unbinding an SMPP session!")
+	}
+
+	return messageID, nil
+}
+
+// smppEncode chooses the default alphabet for plain ASCII texts and UCS2 for the rest.
+func smppEncode(text string) (uint8, []byte) {
+	ascii := true
+	for _, r := range text {
+		if r > 0x7f {
+			ascii = false
+			break
+		}
+	}
+
+	if ascii {
+		return smppDataCodingDefault, []byte(text)
+	}
+
+	encoded := utf16.Encode([]rune(text))
+	b := make([]byte, 2*len(encoded))
+	for i, c := range encoded {
+		binary.BigEndian.PutUint16(b[2*i:], c)
+	}
+
+	return smppDataCodingUCS2, b
+}
+
+type smppBody struct {
+	bytes.Buffer
+}
+
+func (b *smppBody) cString(s string) {
+	b.WriteString(s)
+	b.WriteByte(0)
+}
+
+func (b *smppBody) byte(v uint8) {
+	b.WriteByte(v)
+}
+
+func (b *smppBody) tlv(tag uint16, value []byte) {
+	var header [4]byte
+	binary.BigEndian.PutUint16(header[0:], tag)
+	binary.BigEndian.PutUint16(header[2:], uint16(len(value)))
+	b.Write(header[:])
+	b.Write(value)
+}
+
+type smppPDU struct {
+	commandID uint32
+	status    uint32
+	sequence  uint32
+	body      []byte
+}
+
+type smppSession struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	sequence uint32
+}
+
+// call sends a request and waits for the response with the same sequence number,
+// answering enquire_link requests of the SMSC meanwhile.
+func (s *smppSession) call(commandID, respID uint32, body []byte) (*smppPDU, error) {
+	s.sequence++
+	if err := s.write(&smppPDU{commandID: commandID, sequence: s.sequence, body: body}); err != nil {
+		return nil, err
+	}
+
+	for {
+		pdu, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+
+		if pdu.commandID == smppEnquireLink {
+			if err := s.write(&smppPDU{commandID: smppEnquireLinkResp, sequence: pdu.sequence}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if pdu.sequence != s.sequence {
+			continue
+		}
+
+		if pdu.commandID == smppGenericNack {
+			return nil, fmt.Errorf("generic_nack with status 0x%08x", pdu.status)
+		}
+		if pdu.commandID != respID {
+			return nil, fmt.Errorf("unexpected command 0x%08x", pdu.commandID)
+		}
+		if pdu.status != 0 {
+			return nil, fmt.Errorf("command status 0x%08x", pdu.status)
+		}
+
+		return pdu, nil
+	}
+}
+
+func (s *smppSession) write(pdu *smppPDU) error {
+	b := make([]byte, 16+len(pdu.body))
+	binary.BigEndian.PutUint32(b[0:], uint32(len(b)))
+	binary.BigEndian.PutUint32(b[4:], pdu.commandID)
+	binary.BigEndian.PutUint32(b[8:], pdu.status)
+	binary.BigEndian.PutUint32(b[12:], pdu.sequence)
+	copy(b[16:], pdu.body)
+
+	_, err := s.conn.Write(b)
+	return err
+}
+
+func (s *smppSession) read() (*smppPDU, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(s.reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:])
+	if length < 16 || length > smppMaxPDULength {
+		return nil, fmt.Errorf("invalid pdu length %d", length)
+	}
+
+	pdu := &smppPDU{
+		commandID: binary.BigEndian.Uint32(header[4:]),
+		status:    binary.BigEndian.Uint32(header[8:]),
+		sequence:  binary.BigEndian.Uint32(header[12:]),
+		body:      make([]byte, length-16),
+	}
+	if _, err := io.ReadFull(s.reader, pdu.body); err != nil {
+		return nil, err
+	}
+
+	return pdu, nil
+}
@@ -0,0 +1,323 @@
+package sms
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"trainee-assignment-backend/internal/domain"
+)
+
+// smppSubmit is what a fake SMSC got in submit_sm.
+type smppSubmit struct {
+	sourceTON  uint8
+	sourceAddr string
+	destTON    uint8
+	destAddr   string
+	dataCoding uint8
+	message    []byte
+}
+
+// fakeSMSC accepts a single transmitter session.
+type fakeSMSC struct {
+	listener net.Listener
+
+	bindStatus   uint32
+	submitStatus uint32
+	enquireLink  bool
+
+	systemID string
+	password string
+	submit   *smppSubmit
+	unbound  bool
+	err      error
+	done     chan struct{}
+}
+
+func newFakeSMSC(t *testing.T) *fakeSMSC {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+
+	return &fakeSMSC{
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+}
+
+func (f *fakeSMSC) serve() {
+	defer close(f.done)
+	f.err = f.session()
+}
+
+func (f *fakeSMSC) session() error {
+	conn, err := f.listener.Accept()
+	if err != nil {
+		return err
+	}
+
+	//noinspection ALL
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return err
+	}
+
+	s := &smppSession{conn: conn, reader: bufio.NewReader(conn)}
+	for {
+		pdu, err := s.read()
+		if err != nil {
+			return err
+		}
+
+		switch pdu.commandID {
+		case smppBindTransmitter:
+			body := bytes.NewBuffer(pdu.body)
+			f.systemID = readCString(body)
+			f.password = readCString(body)
+
+			var resp smppBody
+			resp.cString("smsc")
+			if err := s.write(&smppPDU{commandID: smppBindTransmitterResp, status: f.bindStatus, sequence: pdu.sequence, body: resp.Bytes()}); err != nil {
+				return err
+			}
+			if f.bindStatus != 0 {
+				return nil
+			}
+		case smppSubmitSM:
+			f.submit = parseSubmitSM(pdu.body)
+
+			// The driver has to answer a keep-alive of the SMSC while waiting
+			if f.enquireLink {
+				if err := s.write(&smppPDU{commandID: smppEnquireLink, sequence: 100}); err != nil {
+					return err
+				}
+				if resp, err := s.read(); err != nil {
+					return err
+				} else if resp.commandID != smppEnquireLinkResp || resp.sequence != 100 {
+					return errUnexpectedPDU
+				}
+			}
+
+			var resp smppBody
+			if f.submitStatus == 0 {
+				resp.cString("msg-1")
+			}
+			if err := s.write(&smppPDU{commandID: smppSubmitSMResp, status: f.submitStatus, sequence: pdu.sequence, body: resp.Bytes()}); err != nil {
+				return err
+			}
+			if f.submitStatus != 0 {
+				return nil
+			}
+		case smppUnbind:
+			f.unbound = true
+			return s.write(&smppPDU{commandID: smppUnbindResp, sequence: pdu.sequence})
+		default:
+			return errUnexpectedPDU
+		}
+	}
+}
+
+var errUnexpectedPDU = errors.New("unexpected pdu")
+
+func readCString(b *bytes.Buffer) string {
+	s, _ := b.ReadString(0)
+	return string(bytes.TrimSuffix([]byte(s), []byte{0}))
+}
+
+func parseSubmitSM(body []byte) *smppSubmit {
+	b := bytes.NewBuffer(body)
+	s := &smppSubmit{}
+
+	readCString(b) // service_type
+	s.sourceTON, _ = b.ReadByte()
+	_, _ = b.ReadByte() // source_addr_npi
+	s.sourceAddr = readCString(b)
+	s.destTON, _ = b.ReadByte()
+	_, _ = b.ReadByte() // dest_addr_npi
+	s.destAddr = readCString(b)
+	b.Next(3) // esm_class, protocol_id, priority_flag
+	readCString(b)
+	readCString(b)
+	b.Next(2) // registered_delivery, replace_if_present_flag
+	s.dataCoding, _ = b.ReadByte()
+	_, _ = b.ReadByte() // sm_default_msg_id
+
+	length, _ := b.ReadByte()
+	s.message = b.Next(int(length))
+
+	// message_payload TLV of long messages
+	if length == 0 && b.Len() >= 4 {
+		header := b.Next(4)
+		if binary.BigEndian.Uint16(header) == smppTagMessagePayload {
+			s.message = b.Next(int(binary.BigEndian.Uint16(header[2:])))
+		}
+	}
+
+	return s
+}
+
+func TestSMPPDriverSendSMS(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), smppMaxShortMessage+1)
+
+	tests := []struct {
+		name         string
+		destTON      uint8
+		phone        string
+		text         string
+		bindStatus   uint32
+		submitStatus uint32
+		enquireLink  bool
+
+		wantAddr       string
+		wantDataCoding uint8
+		wantMessage    []byte
+		wantErr        error
+	}{
+		{
+			name:           "ascii",
+			destTON:        1,
+			phone:          "+79161234567",
+			text:           "code 1234",
+			wantAddr:       "79161234567",
+			wantDataCoding: smppDataCodingDefault,
+			wantMessage:    []byte("code 1234"),
+		},
+		{
+			name:           "ucs2",
+			destTON:        1,
+			phone:          "+79161234567",
+			text:           "Код",
+			wantAddr:       "79161234567",
+			wantDataCoding: smppDataCodingUCS2,
+			wantMessage:    []byte{0x04, 0x1a, 0x04, 0x3e, 0x04, 0x34},
+		},
+		{
+			name:           "long message in the payload",
+			destTON:        1,
+			phone:          "+79161234567",
+			text:           string(long),
+			wantAddr:       "79161234567",
+			wantDataCoding: smppDataCodingDefault,
+			wantMessage:    long,
+		},
+		{
+			name:           "national address",
+			destTON:        smppTONNational,
+			phone:          "+79161234567",
+			text:           "code 1234",
+			wantAddr:       "9161234567",
+			wantDataCoding: smppDataCodingDefault,
+			wantMessage:    []byte("code 1234"),
+		},
+		{
+			name:           "enquire link while waiting",
+			destTON:        1,
+			phone:          "+79161234567",
+			text:           "code 1234",
+			enquireLink:    true,
+			wantAddr:       "79161234567",
+			wantDataCoding: smppDataCodingDefault,
+			wantMessage:    []byte("code 1234"),
+		},
+		{
+			name:       "bind is rejected",
+			destTON:    1,
+			phone:      "+79161234567",
+			text:       "code 1234",
+			bindStatus: 0x0000000e, // ESME_RINVPASWD
+			wantErr:    domain.ErrInternalSMS,
+		},
+		{
+			name:         "submit is rejected",
+			destTON:      1,
+			phone:        "+79161234567",
+			text:         "code 1234",
+			submitStatus: 0x0000000b, // ESME_RINVDSTADR
+			wantAddr:     "79161234567",
+			wantErr:      domain.ErrInternalSMS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smsc := newFakeSMSC(t)
+			defer smsc.listener.Close()
+
+			smsc.bindStatus = tt.bindStatus
+			smsc.submitStatus = tt.submitStatus
+			smsc.enquireLink = tt.enquireLink
+			go smsc.serve()
+
+			sender, err := newSMPPDriver(newTestLogger(), &Config{SMPP: &SMPPConfig{
+				Addr:       smsc.listener.Addr().String(),
+				SystemID:   "esme",
+				Password:   "secret",
+				SourceAddr: "WomanClub",
+				SourceTON:  5,
+				DestTON:    tt.destTON,
+				DestNPI:    1,
+				Timeout:    5 * time.Second,
+			}})
+			if err != nil {
+				t.Fatalf("newSMPPDriver() error = %v", err)
+			}
+
+			receipt, err := sender.SendSMS(tt.phone, tt.text)
+			if err != tt.wantErr {
+				t.Fatalf("SendSMS() error = %v, want %v", err, tt.wantErr)
+			}
+
+			<-smsc.done
+			if smsc.err != nil {
+				t.Fatalf("SMSC error = %v", smsc.err)
+			}
+
+			if smsc.systemID != "esme" || smsc.password != "secret" {
+				t.Errorf("bind = %q/%q, want esme/secret", smsc.systemID, smsc.password)
+			}
+
+			if tt.wantAddr != "" {
+				if smsc.submit == nil {
+					t.Fatal("submit_sm is not received")
+				}
+				if smsc.submit.destAddr != tt.wantAddr || smsc.submit.destTON != tt.destTON {
+					t.Errorf("destination = %q (ton %d), want %q (ton %d)",
+						smsc.submit.destAddr, smsc.submit.destTON, tt.wantAddr, tt.destTON)
+				}
+				if smsc.submit.sourceAddr != "WomanClub" || smsc.submit.sourceTON != 5 {
+					t.Errorf("source = %q (ton %d), want WomanClub (ton 5)", smsc.submit.sourceAddr, smsc.submit.sourceTON)
+				}
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if smsc.submit.dataCoding != tt.wantDataCoding {
+				t.Errorf("data_coding = %d, want %d", smsc.submit.dataCoding, tt.wantDataCoding)
+			}
+			if !bytes.Equal(smsc.submit.message, tt.wantMessage) {
+				t.Errorf("message = %x, want %x", smsc.submit.message, tt.wantMessage)
+			}
+			if !smsc.unbound {
+				t.Error("session is not unbound")
+			}
+			if receipt.Provider != "smpp" || receipt.MessageID != "msg-1" {
+				t.Errorf("receipt = %+v, want message id msg-1", receipt)
+			}
+		})
+	}
+}
+
+func TestNewSMPPDriverWithoutAddr(t *testing.T) {
+	if _, err := newSMPPDriver(newTestLogger(), &Config{SMPP: &SMPPConfig{}}); err == nil {
+		t.Fatal("newSMPPDriver() error = nil, want an error")
+	}
+}
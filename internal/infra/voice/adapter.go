@@ -0,0 +1,75 @@
+package voice
+
+import (
+	"fmt"
+	"trainee-assignment-backend/internal/domain"
+	"trainee-assignment-backend/pkg/httpapi"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// adapter places calls reading a text out loud by text-to-speech of a provider.
+type adapter struct {
+	logger *logrus.Logger
+	config *Config
+	client *httpapi.Client
+}
+
+type templateData struct {
+	Phone string
+	Text  string
+}
+
+// Creating a new voice call adapter with the configured driver.
+func NewAdapter(logger *logrus.Logger, config *Config) (domain.VoiceCaller, error) {
+	a := &adapter{
+		logger: logger,
+		config: config,
+	}
+
+	switch config.Driver {
+	case "log":
+	case "http":
+		client, err := httpapi.NewClient(config.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("voice http driver: %w", err)
+		}
+		a.client = client
+	default:
+		return nil, fmt.Errorf("unknown voice driver %q", config.Driver)
+	}
+
+	return a, nil
+}
+
+func (a *adapter) Call(phone, text string) (*domain.MessageReceipt, error) {
+	if a.client == nil {
+		messageID := uuid.New().String()
+
+		a.logger.WithFields(logrus.Fields{
+			"message_id": messageID,
+			"phone":      phone,
+			"text":       text,
+		}).Info("Voice call is sent to the log.")
+
+		return &domain.MessageReceipt{
+			Provider:  "log",
+			MessageID: messageID,
+		}, nil
+	}
+
+	messageID, err := a.client.Call(templateData{
+		Phone: phone,
+		Text:  text,
+	})
+	if err != nil {
+		a.logger.WithError(err).Error("Error while placing a voice call through the provider API!")
+		return nil, domain.ErrInternalVoice
+	}
+
+	return &domain.MessageReceipt{
+		Provider:  "http",
+		MessageID: messageID,
+	}, nil
+}
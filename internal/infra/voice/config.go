@@ -0,0 +1,9 @@
+package voice
+
+import "trainee-assignment-backend/pkg/httpapi"
+
+type Config struct {
+	Driver string `long:"driver" env:"DRIVER" description:"Voice call driver (log, http)" default:"log"`
+
+	HTTP *httpapi.Config `group:"Voice HTTP driver args" namespace:"http" env-namespace:"HTTP"`
+}
@@ -0,0 +1,302 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"trainee-assignment-backend/internal/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+type adapter struct {
+	logger   *logrus.Logger
+	config   *Config
+	rpIDHash [32]byte
+}
+
+func NewAdapter(logger *logrus.Logger, config *Config) domain.WebAuthn {
+	return &adapter{
+		logger:   logger,
+		config:   config,
+		rpIDHash: sha256.Sum256([]byte(config.RPID)),
+	}
+}
+
+func (a *adapter) NewRegistrationOptions(
+	user *domain.User,
+	exclude []*domain.WebAuthnCredential,
+) (*domain.WebAuthnRegistrationOptions, error) {
+	challenge, err := a.challenge()
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := user.Phone
+	if user.FirstName != nil {
+		displayName = *user.FirstName
+	}
+
+	excludeIDs := make([][]byte, 0, len(exclude))
+	for _, c := range exclude {
+		excludeIDs = append(excludeIDs, c.CredentialID)
+	}
+
+	return &domain.WebAuthnRegistrationOptions{
+		Challenge:            challenge,
+		RPID:                 a.config.RPID,
+		RPName:               a.config.RPName,
+		UserHandle:           UserHandle(user.ID),
+		UserName:             user.Phone,
+		UserDisplayName:      displayName,
+		ExcludeCredentialIDs: excludeIDs,
+		UserVerification:     a.config.UserVerification,
+		Timeout:              a.config.Timeout,
+	}, nil
+}
+
+// VerifyRegistration checks an attestation response. Only the "none" attestation conveyance is requested,
+// so attestation statements are not verified against any trust anchors.
+func (a *adapter) VerifyRegistration(
+	challenge []byte,
+	attestation *domain.WebAuthnAttestation,
+) (*domain.WebAuthnCredential, error) {
+	if err := a.verifyClientData(attestation.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		a.logger.WithError(err).Info("Invalid client data of a WebAuthn registration!")
+		return nil, domain.ErrWebAuthnVerificationFailed
+	}
+
+	v, _, err := cborDecode(attestation.AttestationObject)
+	if err != nil {
+		a.logger.WithError(err).Info("Invalid attestation object!")
+		return nil, domain.ErrWebAuthnVerificationFailed
+	}
+
+	object, ok := v.(map[interface{}]interface{})
+	if !ok {
+		a.logger.Info("Attestation object is not a map!")
+		return nil, domain.ErrWebAuthnVerificationFailed
+	}
+
+	rawAuthData, _ := object["authData"].([]byte)
+	authData, err := a.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		a.logger.WithError(err).Info("Invalid authenticator data of a WebAuthn registration!")
+		return nil, domain.ErrWebAuthnVerificationFailed
+	}
+
+	if authData.credentialID == nil {
+		a.logger.Info("No attested credential data in a WebAuthn registration!")
+		return nil, domain.ErrWebAuthnVerificationFailed
+	}
+
+	if !bytes.Equal(authData.credentialID, attestation.CredentialID) {
+		a.logger.Info("Credential id mismatch in a WebAuthn registration!")
+		return nil, domain.ErrWebAuthnVerificationFailed
+	}
+
+	if _, err := parseCOSEKey(authData.publicKey); err != nil {
+		a.logger.WithError(err).Info("Unsupported WebAuthn credential public key!")
+		return nil, domain.ErrWebAuthnVerificationFailed
+	}
+
+	return &domain.WebAuthnCredential{
+		CredentialID: authData.credentialID,
+		PublicKey:    authData.publicKey,
+		SignCount:    authData.signCount,
+		AAGUID:       authData.aaguid,
+	}, nil
+}
+
+func (a *adapter) NewLoginOptions(allow []*domain.WebAuthnCredential) (*domain.WebAuthnLoginOptions, error) {
+	challenge, err := a.challenge()
+	if err != nil {
+		return nil, err
+	}
+
+	allowIDs := make([][]byte, 0, len(allow))
+	for _, c := range allow {
+		allowIDs = append(allowIDs, c.CredentialID)
+	}
+
+	return &domain.WebAuthnLoginOptions{
+		Challenge:          challenge,
+		RPID:               a.config.RPID,
+		AllowCredentialIDs: allowIDs,
+		UserVerification:   a.config.UserVerification,
+		Timeout:            a.config.Timeout,
+	}, nil
+}
+
+// VerifyLogin also tells whether the authenticator verified the user with a PIN or biometrics,
+// a user who is only present proves nothing but holding the device.
+func (a *adapter) VerifyLogin(
+	challenge []byte,
+	credential *domain.WebAuthnCredential,
+	assertion *domain.WebAuthnAssertion,
+) (uint32, bool, error) {
+	if err := a.verifyClientData(assertion.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		a.logger.WithError(err).Info("Invalid client data of a WebAuthn login!")
+		return 0, false, domain.ErrWebAuthnVerificationFailed
+	}
+
+	if len(assertion.UserHandle) != 0 && !bytes.Equal(assertion.UserHandle, UserHandle(credential.UserID)) {
+		a.logger.Info("User handle mismatch in a WebAuthn login!")
+		return 0, false, domain.ErrWebAuthnVerificationFailed
+	}
+
+	authData, err := a.parseAuthenticatorData(assertion.AuthenticatorData)
+	if err != nil {
+		a.logger.WithError(err).Info("Invalid authenticator data of a WebAuthn login!")
+		return 0, false, domain.ErrWebAuthnVerificationFailed
+	}
+
+	key, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		a.logger.WithError(err).Error("Error while parsing a stored WebAuthn public key!")
+		return 0, false, domain.ErrInternalWebAuthn
+	}
+
+	clientDataHash := sha256.Sum256(assertion.ClientDataJSON)
+	signed := append(append([]byte(nil), assertion.AuthenticatorData...), clientDataHash[:]...)
+	if !key.verify(signed, assertion.Signature) {
+		a.logger.Info("Invalid signature of a WebAuthn login!")
+		return 0, false, domain.ErrWebAuthnVerificationFailed
+	}
+
+	// A counter that doesn't grow is a sign of a cloned authenticator
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		a.logger.WithField("credential_id", credential.ID).Warn("WebAuthn signature counter didn't increase!")
+		return 0, false, domain.ErrWebAuthnVerificationFailed
+	}
+
+	return authData.signCount, authData.flags&flagUserVerified != 0, nil
+}
+
+// UserHandle is the user id given to authenticators.
+func UserHandle(userID int) []byte {
+	return []byte(strconv.Itoa(userID))
+}
+
+func (a *adapter) challenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		a.logger.WithError(err).Error("Error while generating a WebAuthn challenge!")
+		return nil, domain.ErrInternalWebAuthn
+	}
+
+	return challenge, nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (a *adapter) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var c clientData
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return err
+	}
+
+	if c.Type != ceremony {
+		return fmt.Errorf("unexpected ceremony type %q", c.Type)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(c.Challenge)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return errors.New("challenge mismatch")
+	}
+
+	if c.CrossOrigin {
+		return errors.New("cross-origin ceremonies are not allowed")
+	}
+
+	for _, origin := range a.config.Origins {
+		if c.Origin == origin {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unexpected origin %q", c.Origin)
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (a *adapter) parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	if subtle.ConstantTimeCompare(data[:32], a.rpIDHash[:]) != 1 {
+		return nil, errors.New("relying party id mismatch")
+	}
+
+	d := &authenticatorData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if d.flags&flagUserPresent == 0 {
+		return nil, errors.New("user is not present")
+	}
+	if a.config.UserVerification && d.flags&flagUserVerified == 0 {
+		return nil, errors.New("user is not verified")
+	}
+
+	if d.flags&flagAttestedCredentialData == 0 {
+		return d, nil
+	}
+
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("attested credential data is too short")
+	}
+
+	d.aaguid = append([]byte(nil), rest[:16]...)
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+
+	if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+		return nil, errors.New("invalid credential id length")
+	}
+
+	d.credentialID = append([]byte(nil), rest[:idLen]...)
+	rest = rest[idLen:]
+
+	// The key is followed by optional extensions, so it is cut by its encoded length
+	_, extensions, err := cborDecode(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	d.publicKey = append([]byte(nil), rest[:len(rest)-len(extensions)]...)
+
+	return d, nil
+}
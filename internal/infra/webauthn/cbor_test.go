@@ -0,0 +1,133 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCBORDecode(t *testing.T) {
+	// Examples of RFC 7049 appendix A
+	tests := []struct {
+		hex  string
+		want interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"190100", int64(256)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"20", int64(-1)},
+		{"3863", int64(-100)},
+		{"3903e7", int64(-1000)},
+		{"40", []byte(nil)},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"60", ""},
+		{"6449455446", "IETF"},
+		{"63e282ac", "€"},
+		{"80", []interface{}{}},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"8301820203820405", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"a0", map[interface{}]interface{}{}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f7", nil},
+		{"f93c00", 1.0},
+		{"f93e00", 1.5},
+		{"f97bff", 65504.0},
+		{"f90001", 5.960464477539063e-08},
+		{"f9c400", -4.0},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+		{"f97c00", math.Inf(1)},
+		{"f9fc00", math.Inf(-1)},
+		// Tags are skipped
+		{"c11a514b67b0", int64(1363896240)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+
+			got, rest, err := cborDecode(data)
+			if err != nil {
+				t.Fatalf("cborDecode() error = %v", err)
+			}
+
+			if len(rest) != 0 {
+				t.Errorf("rest = %x, want none", rest)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cborDecode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCBORDecodeNaN(t *testing.T) {
+	got, _, err := cborDecode([]byte{0xf9, 0x7e, 0x00})
+	if err != nil {
+		t.Fatalf("cborDecode() error = %v", err)
+	}
+
+	if f, ok := got.(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("cborDecode() = %#v, want NaN", got)
+	}
+}
+
+func TestCBORDecodeRest(t *testing.T) {
+	// Attested credential data is followed by extensions
+	got, rest, err := cborDecode([]byte{0x01, 0xa1, 0x01, 0x02})
+	if err != nil {
+		t.Fatalf("cborDecode() error = %v", err)
+	}
+
+	if got != int64(1) {
+		t.Errorf("cborDecode() = %#v, want 1", got)
+	}
+
+	if !bytes.Equal(rest, []byte{0xa1, 0x01, 0x02}) {
+		t.Errorf("rest = %x, want a10102", rest)
+	}
+}
+
+func TestCBORDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"empty", ""},
+		{"truncated argument", "1901"},
+		{"truncated byte string", "4401"},
+		{"truncated text string", "6449"},
+		{"truncated array", "8301"},
+		{"truncated map", "a201"},
+		{"array longer than the data", "9b7fffffffffffffff"},
+		{"byte string longer than the data", "5bffffffffffffffff"},
+		{"indefinite length", "9f01ff"},
+		{"reserved additional information", "1c"},
+		{"integer overflow", "1bffffffffffffffff"},
+		{"negative integer overflow", "3bffffffffffffffff"},
+		{"array map key", "a18001"},
+		{"unsupported simple value", "f8ff"},
+		{"nesting is too deep", strings.Repeat("81", cborMaxDepth+1) + "00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+
+			if v, _, err := cborDecode(data); err == nil {
+				t.Errorf("cborDecode() = %#v, want an error", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package webauthn
+
+import "time"
+
+type Config struct {
+	RPID             string        `long:"rp-id" env:"RP_ID" description:"Relying party id, the effective domain of the frontend" required:"yes"`
+	RPName           string        `long:"rp-name" env:"RP_NAME" description:"Relying party name shown by authenticators" default:"Woman Club"`
+	Origins          []string      `long:"origin" env:"ORIGINS" env-delim:"," description:"Allowed origins of the ceremonies" required:"yes"`
+	Timeout          time.Duration `long:"timeout" env:"TIMEOUT" description:"Time given to complete a ceremony" default:"5m"`
+	UserVerification bool          `long:"user-verification" env:"USER_VERIFICATION" description:"Require user verification (PIN, biometrics) by authenticators"`
+}
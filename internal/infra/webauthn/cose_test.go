@@ -0,0 +1,198 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+)
+
+// cborHead encodes the initial byte of an item with its argument.
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		b := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		return b
+	default:
+		b := []byte{major<<5 | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		return b
+	}
+}
+
+func cborInt(v int64) []byte {
+	if v < 0 {
+		return cborHead(1, uint64(-1-v))
+	}
+
+	return cborHead(0, uint64(v))
+}
+
+// coseKeyBytes encodes a map of integer labels to integers or byte strings.
+func coseKeyBytes(labels []int64, values []interface{}) []byte {
+	b := cborHead(5, uint64(len(labels)))
+	for i, label := range labels {
+		b = append(b, cborInt(label)...)
+
+		switch v := values[i].(type) {
+		case int64:
+			b = append(b, cborInt(v)...)
+		case []byte:
+			b = append(b, cborHead(2, uint64(len(v)))...)
+			b = append(b, v...)
+		}
+	}
+
+	return b
+}
+
+func ec2Key(key *ecdsa.PublicKey, crv int64) []byte {
+	return coseKeyBytes(
+		[]int64{1, 3, -1, -2, -3},
+		[]interface{}{coseKtyEC2, coseAlgES256, crv, key.X.FillBytes(make([]byte, 32)), key.Y.FillBytes(make([]byte, 32))},
+	)
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	data := []byte("authenticator data and client data hash")
+	digest := sha256.Sum256(data)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecSignature, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaSignature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+
+	edPublicKey, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edSignature := ed25519.Sign(edPrivateKey, data)
+
+	tests := []struct {
+		name      string
+		key       []byte
+		alg       int64
+		signature []byte
+	}{
+		{
+			name:      "ES256",
+			key:       ec2Key(&ecKey.PublicKey, coseCrvP256),
+			alg:       coseAlgES256,
+			signature: ecSignature,
+		},
+		{
+			name: "RS256",
+			key: coseKeyBytes(
+				[]int64{1, 3, -1, -2},
+				[]interface{}{coseKtyRSA, coseAlgRS256, rsaKey.N.Bytes(), big.NewInt(int64(rsaKey.E)).Bytes()},
+			),
+			alg:       coseAlgRS256,
+			signature: rsaSignature,
+		},
+		{
+			name: "EdDSA",
+			key: coseKeyBytes(
+				[]int64{1, 3, -1, -2},
+				[]interface{}{coseKtyOKP, coseAlgEdDSA, coseCrvEd25519, []byte(edPublicKey)},
+			),
+			alg:       coseAlgEdDSA,
+			signature: edSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := parseCOSEKey(tt.key)
+			if err != nil {
+				t.Fatalf("parseCOSEKey() error = %v", err)
+			}
+
+			if k.alg != tt.alg {
+				t.Errorf("alg = %d, want %d", k.alg, tt.alg)
+			}
+
+			if !k.verify(data, tt.signature) {
+				t.Error("verify() = false for a valid signature")
+			}
+
+			if k.verify([]byte("other data"), tt.signature) {
+				t.Error("verify() = true for other data")
+			}
+		})
+	}
+}
+
+func TestParseCOSEKeyErrors(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	offCurve := *ecKey
+	offCurve.PublicKey.Y = new(big.Int).Add(ecKey.Y, big.NewInt(1))
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{
+			name: "not cbor",
+			key:  []byte{0x1c},
+		},
+		{
+			name: "not a map",
+			key:  cborInt(2),
+		},
+		{
+			name: "unsupported algorithm",
+			key:  coseKeyBytes([]int64{1, 3}, []interface{}{coseKtyEC2, int64(-35)}),
+		},
+		{
+			name: "key type of another algorithm",
+			key:  coseKeyBytes([]int64{1, 3}, []interface{}{coseKtyRSA, coseAlgES256}),
+		},
+		{
+			name: "EC2 key of another curve",
+			key:  ec2Key(&ecKey.PublicKey, 2),
+		},
+		{
+			name: "EC2 point off the curve",
+			key:  ec2Key(&offCurve.PublicKey, coseCrvP256),
+		},
+		{
+			name: "short EC2 coordinate",
+			key: coseKeyBytes(
+				[]int64{1, 3, -1, -2, -3},
+				[]interface{}{coseKtyEC2, coseAlgES256, coseCrvP256, make([]byte, 31), make([]byte, 32)},
+			),
+		},
+		{
+			name: "short RSA modulus",
+			key: coseKeyBytes(
+				[]int64{1, 3, -1, -2},
+				[]interface{}{coseKtyRSA, coseAlgRS256, make([]byte, 128), []byte{1, 0, 1}},
+			),
+		},
+		{
+			name: "OKP key of another curve",
+			key: coseKeyBytes(
+				[]int64{1, 3, -1, -2},
+				[]interface{}{coseKtyOKP, coseAlgEdDSA, int64(4), make([]byte, ed25519.PublicKeySize)},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCOSEKey(tt.key); err == nil {
+				t.Error("parseCOSEKey() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+DROP TRIGGER update_updated_at ON outbox_messages;
+
+DROP TABLE if EXISTS outbox_messages;
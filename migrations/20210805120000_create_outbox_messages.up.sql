@@ -0,0 +1,33 @@
+-- Messages waiting to be sent by outbox workers.
+-- Status meaning:
+-- pending    - waiting for the next attempt at next_attempt_at
+-- processing - claimed by a worker until locked_until
+-- sent       - accepted by a provider
+-- dead       - attempts are exhausted or the message is expired
+CREATE TABLE IF NOT EXISTS outbox_messages
+(
+    id                  INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    kind                TEXT      NOT NULL,
+    recipient           TEXT      NOT NULL,
+    payload             JSONB     NOT NULL DEFAULT '{}',
+    status              TEXT      NOT NULL DEFAULT 'pending',
+    attempts            INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at     TIMESTAMP NOT NULL DEFAULT now(),
+    locked_until        TIMESTAMP,
+    expires_at          TIMESTAMP,
+    last_error          TEXT,
+    provider            TEXT,
+    provider_message_id TEXT,
+    sent_at             TIMESTAMP,
+    created_at          TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_messages_status_next_attempt_at_idx
+    ON outbox_messages (status, next_attempt_at);
+
+CREATE TRIGGER update_updated_at
+    BEFORE UPDATE
+    ON outbox_messages
+    FOR EACH ROW
+EXECUTE PROCEDURE moddatetime(updated_at);
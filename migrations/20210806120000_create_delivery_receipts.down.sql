@@ -0,0 +1,8 @@
+DROP TABLE if EXISTS delivery_receipts;
+
+DROP INDEX if EXISTS outbox_messages_provider_message_id_idx;
+
+DROP INDEX if EXISTS outbox_messages_request_id_idx;
+
+ALTER TABLE outbox_messages
+    DROP COLUMN IF EXISTS request_id;
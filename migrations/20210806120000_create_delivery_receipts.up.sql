@@ -0,0 +1,23 @@
+-- Links OTP messages with the login or registration request they belong to
+ALTER TABLE outbox_messages
+    ADD COLUMN IF NOT EXISTS request_id UUID;
+
+CREATE INDEX IF NOT EXISTS outbox_messages_request_id_idx
+    ON outbox_messages (request_id);
+
+CREATE INDEX IF NOT EXISTS outbox_messages_provider_message_id_idx
+    ON outbox_messages (provider, provider_message_id);
+
+-- Delivery reports received from SMS providers
+CREATE TABLE IF NOT EXISTS delivery_receipts
+(
+    id                  INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    outbound_message_id INTEGER   NOT NULL REFERENCES outbox_messages (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    status              TEXT      NOT NULL,
+    error_code          TEXT,
+    payload             JSONB     NOT NULL DEFAULT '{}',
+    created_at          TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS delivery_receipts_outbound_message_id_idx
+    ON delivery_receipts (outbound_message_id, created_at);
@@ -0,0 +1,3 @@
+DROP TABLE if EXISTS recovery_codes;
+
+DROP TABLE if EXISTS user_totp;
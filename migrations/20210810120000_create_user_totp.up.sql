@@ -0,0 +1,19 @@
+-- TOTP (RFC 6238) authenticators, confirmed_at is set after the first valid code.
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id      INTEGER PRIMARY KEY REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    secret       TEXT      NOT NULL,
+    confirmed_at TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Hashes of single-use recovery codes of TOTP authenticators.
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id    INTEGER   NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    code_hash  TEXT      NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1 @@
+DROP TABLE if EXISTS webauthn_credentials;
@@ -0,0 +1,14 @@
+-- WebAuthn (passkey) credentials, public_key is a COSE key.
+CREATE TABLE IF NOT EXISTS webauthn_credentials
+(
+    id            INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id       INTEGER   NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    credential_id BYTEA     NOT NULL UNIQUE,
+    public_key    BYTEA     NOT NULL,
+    sign_count    BIGINT    NOT NULL DEFAULT 0,
+    aaguid        BYTEA,
+    last_used_at  TIMESTAMP,
+    created_at    TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
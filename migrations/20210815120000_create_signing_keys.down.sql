@@ -0,0 +1 @@
+DROP TABLE if EXISTS signing_keys;
@@ -0,0 +1,12 @@
+-- JWT signing keys. A key is published before activates_at and is kept for verification until expires_at.
+CREATE TABLE IF NOT EXISTS signing_keys
+(
+    kid          TEXT PRIMARY KEY,
+    algorithm    TEXT      NOT NULL,
+    private_key  BYTEA     NOT NULL,
+    activates_at TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS signing_keys_activates_at_idx ON signing_keys (activates_at);
@@ -0,0 +1 @@
+DROP TABLE if EXISTS oauth_clients;
@@ -0,0 +1,10 @@
+-- Relying parties of the OpenID Connect provider. Public clients have no secret and rely on PKCE.
+CREATE TABLE IF NOT EXISTS oauth_clients
+(
+    client_id     TEXT PRIMARY KEY,
+    name          TEXT      NOT NULL,
+    secret_hash   TEXT,
+    redirect_uris TEXT[]    NOT NULL DEFAULT '{}',
+    scopes        TEXT[]    NOT NULL DEFAULT '{openid}',
+    created_at    TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,5 @@
+DROP TABLE if EXISTS audit_events;
+
+ALTER TABLE oauth_clients
+    DROP COLUMN IF EXISTS tls_client_auth_subject,
+    DROP COLUMN IF EXISTS grant_types;
@@ -0,0 +1,20 @@
+-- Service clients use the client_credentials grant and may authenticate with a TLS client certificate (RFC 8705).
+ALTER TABLE oauth_clients
+    ADD COLUMN IF NOT EXISTS grant_types             TEXT[] NOT NULL DEFAULT '{authorization_code}',
+    ADD COLUMN IF NOT EXISTS tls_client_auth_subject TEXT UNIQUE;
+
+-- Security-relevant events.
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id         INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    type       TEXT      NOT NULL,
+    user_id    INTEGER REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    client_id  TEXT,
+    ip         TEXT,
+    user_agent TEXT,
+    details    JSONB     NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events (user_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_client_id_idx ON audit_events (client_id, created_at);
@@ -0,0 +1,5 @@
+DROP TABLE if EXISTS user_roles;
+
+DROP TABLE if EXISTS role_permissions;
+
+DROP TABLE if EXISTS roles;
@@ -0,0 +1,40 @@
+-- Status bit 00100000 means the user is blocked by the support staff.
+
+CREATE TABLE IF NOT EXISTS roles
+(
+    id         INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name       TEXT      NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id    INTEGER REFERENCES roles (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id    INTEGER REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    role_id    INTEGER REFERENCES roles (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (name)
+VALUES ('support'),
+       ('admin')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+         CROSS JOIN (VALUES ('users:read'), ('sessions:revoke')) AS p (permission)
+WHERE r.name = 'support'
+UNION ALL
+SELECT r.id, p.permission
+FROM roles r
+         CROSS JOIN (VALUES ('users:read'), ('sessions:revoke'), ('users:block')) AS p (permission)
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+UPDATE role_permissions
+SET permission = 'users:block'
+WHERE permission = 'users:restrict';
+
+UPDATE users
+SET status = status & ~B'11000000';
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status_expires_at;
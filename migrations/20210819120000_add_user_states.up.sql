@@ -0,0 +1,12 @@
+-- Restrictive states use the reserved status bits:
+-- 11100000
+-- ││└───── user is suspended, blocked users are suspended without an expiry
+-- │└────── user is banned
+-- └─────── user is pending deletion
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status_reason     TEXT,
+    ADD COLUMN IF NOT EXISTS status_expires_at TIMESTAMP;
+
+UPDATE role_permissions
+SET permission = 'users:restrict'
+WHERE permission = 'users:block';
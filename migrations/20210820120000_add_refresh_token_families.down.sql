@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS refresh_sessions_family_id_idx;
+
+ALTER TABLE refresh_sessions
+    DROP COLUMN IF EXISTS rotated_at,
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,7 @@
+-- Rotated refresh tokens stay in the family, so a replayed one revokes its descendants.
+ALTER TABLE refresh_sessions
+    ADD COLUMN IF NOT EXISTS family_id  UUID NOT NULL DEFAULT uuid_generate_v4(),
+    ADD COLUMN IF NOT EXISTS parent_id  INTEGER REFERENCES refresh_sessions (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS refresh_sessions_family_id_idx ON refresh_sessions (family_id);
@@ -0,0 +1,2 @@
+ALTER TABLE refresh_sessions
+    DROP COLUMN IF EXISTS client_type;
@@ -0,0 +1,3 @@
+-- Client type selects the session policy.
+ALTER TABLE refresh_sessions
+    ADD COLUMN IF NOT EXISTS client_type TEXT NOT NULL DEFAULT 'web';
@@ -0,0 +1,13 @@
+-- Devices a user has logged in from, a login from an unseen device, city or IP is notified.
+CREATE TABLE IF NOT EXISTS known_devices
+(
+    id            INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id       INTEGER   NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    fingerprint   TEXT      NOT NULL,
+    user_agent    TEXT      NOT NULL,
+    ip            TEXT      NOT NULL,
+    city          TEXT,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_seen_at  TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (user_id, fingerprint)
+);
@@ -0,0 +1,7 @@
+-- Time of the last interactive authentication of a session, sensitive operations require a recent one.
+-- Existing sessions are never recent enough.
+ALTER TABLE refresh_sessions
+    ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP NOT NULL DEFAULT 'epoch';
+
+ALTER TABLE refresh_sessions
+    ALTER COLUMN auth_time SET DEFAULT now();
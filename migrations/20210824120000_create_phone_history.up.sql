@@ -0,0 +1,12 @@
+-- Phone changes of users, the old number is confirmed either by an OTP or by the confirmed email.
+CREATE TABLE IF NOT EXISTS phone_history
+(
+    id               INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id          INTEGER   NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    old_phone        TEXT      NOT NULL,
+    new_phone        TEXT      NOT NULL,
+    old_confirmed_by TEXT      NOT NULL,
+    changed_at       TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS phone_history_user_id_idx ON phone_history (user_id);
@@ -0,0 +1,35 @@
+-- Only Russian mobile numbers fit the old form, others are left as they are.
+-- A number registered in both forms after the migration can't go back to one.
+DO
+$$
+    DECLARE
+        duplicates TEXT;
+    BEGIN
+        SELECT string_agg(phone || ' (users ' || ids || ')', ', ')
+        INTO duplicates
+        FROM (
+                 SELECT right(phone, 10) AS phone, string_agg(id::TEXT, ', ' ORDER BY id) AS ids
+                 FROM users
+                 WHERE phone ~ '^(\+7)?9\d{9}$'
+                 GROUP BY right(phone, 10)
+                 HAVING count(*) > 1
+             ) AS d;
+
+        IF duplicates IS NOT NULL THEN
+            RAISE EXCEPTION 'Phones of several users are the same number: %', duplicates
+                USING HINT = 'Change or delete the phones of all but one user of each number.';
+        END IF;
+    END
+$$;
+
+UPDATE users
+SET phone = right(phone, 10)
+WHERE phone ~ '^\+79\d{9}$';
+
+UPDATE phone_history
+SET old_phone = right(old_phone, 10)
+WHERE old_phone ~ '^\+79\d{9}$';
+
+UPDATE phone_history
+SET new_phone = right(new_phone, 10)
+WHERE new_phone ~ '^\+79\d{9}$';
@@ -0,0 +1,36 @@
+-- Phones are stored in the E.164 form, the old ones are Russian mobile numbers without the country code.
+-- Several forms of the same number can't be merged automatically, accounts of the same person
+-- have to be resolved by hand before the migration.
+DO
+$$
+    DECLARE
+        duplicates TEXT;
+    BEGIN
+        SELECT string_agg(phone || ' (users ' || ids || ')', ', ')
+        INTO duplicates
+        FROM (
+                 SELECT '+7' || right(phone, 10) AS phone, string_agg(id::TEXT, ', ' ORDER BY id) AS ids
+                 FROM users
+                 WHERE phone ~ '^([78]?|\+7)9\d{9}$'
+                 GROUP BY '+7' || right(phone, 10)
+                 HAVING count(*) > 1
+             ) AS d;
+
+        IF duplicates IS NOT NULL THEN
+            RAISE EXCEPTION 'Phones of several users are the same number: %', duplicates
+                USING HINT = 'Change or delete the phones of all but one user of each number.';
+        END IF;
+    END
+$$;
+
+UPDATE users
+SET phone = '+7' || right(phone, 10)
+WHERE phone ~ '^[78]?9\d{9}$';
+
+UPDATE phone_history
+SET old_phone = '+7' || right(old_phone, 10)
+WHERE old_phone ~ '^[78]?9\d{9}$';
+
+UPDATE phone_history
+SET new_phone = '+7' || right(new_phone, 10)
+WHERE new_phone ~ '^[78]?9\d{9}$';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS outbox_messages_recipient_idx;
+DROP INDEX IF EXISTS users_status_expires_at_idx;
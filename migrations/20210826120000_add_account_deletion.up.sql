@@ -0,0 +1,8 @@
+-- Accounts whose grace period is over are picked up by the purge job
+CREATE INDEX IF NOT EXISTS users_status_expires_at_idx
+    ON users (status_expires_at)
+    WHERE status_expires_at IS NOT NULL;
+
+-- Messages are exported and purged by their recipient
+CREATE INDEX IF NOT EXISTS outbox_messages_recipient_idx
+    ON outbox_messages (recipient);
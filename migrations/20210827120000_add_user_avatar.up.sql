@@ -0,0 +1,3 @@
+-- Key prefix of the avatar variants in the blob store
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS avatar TEXT;
@@ -0,0 +1,7 @@
+CREATE INDEX IF NOT EXISTS outbox_messages_recipient_idx
+    ON outbox_messages (recipient);
+
+DROP INDEX IF EXISTS outbox_messages_user_id_idx;
+
+ALTER TABLE outbox_messages
+    DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,9 @@
+-- Phones and emails change hands, so messages belong to the user they were sent to, not to the recipient.
+-- Earlier messages can't be told apart reliably and are left without a user.
+ALTER TABLE outbox_messages
+    ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS outbox_messages_user_id_idx
+    ON outbox_messages (user_id, created_at);
+
+DROP INDEX IF EXISTS outbox_messages_recipient_idx;
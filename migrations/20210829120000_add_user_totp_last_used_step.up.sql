@@ -0,0 +1,3 @@
+-- Time step of the last accepted code, a code is accepted once (RFC 6238 section 5.2).
+ALTER TABLE user_totp
+    ADD COLUMN IF NOT EXISTS last_used_step BIGINT;
@@ -0,0 +1,2 @@
+-- Encrypted keys can't be read without the key-encryption key, a new plaintext key is generated on start.
+DELETE FROM signing_keys;
@@ -0,0 +1,3 @@
+-- Signing keys are encrypted at rest from now on, the plaintext ones are dropped and a new key is
+-- generated on start. Access tokens signed by them are rejected and get refreshed.
+DELETE FROM signing_keys;
@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Client calls a provider API described by configuration only:
+// both the URL and the request body are templates executed with the call data.
+type Client struct {
+	config *Config
+	client *http.Client
+
+	url  *template.Template
+	body *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"urlquery": url.QueryEscape,
+}
+
+// NewClient parses templates of the configuration.
+func NewClient(config *Config) (*Client, error) {
+	if config == nil || config.URL == "" {
+		return nil, errors.New("url is required")
+	}
+
+	urlTemplate, err := template.New("url").Funcs(templateFuncs).Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse url template: %w", err)
+	}
+
+	bodyTemplate, err := template.New("body").Funcs(templateFuncs).Parse(config.RequestTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse request template: %w", err)
+	}
+
+	return &Client{
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+		},
+		url:  urlTemplate,
+		body: bodyTemplate,
+	}, nil
+}
+
+// Call performs a request and returns a message id if the response is successful.
+func (c *Client) Call(data interface{}) (string, error) {
+	var u, body bytes.Buffer
+	if err := c.url.Execute(&u, data); err != nil {
+		return "", fmt.Errorf("cannot execute url template: %w", err)
+	}
+	if err := c.body.Execute(&body, data); err != nil {
+		return "", fmt.Errorf("cannot execute request template: %w", err)
+	}
+
+	var reqBody io.Reader
+	if c.config.Method != http.MethodGet {
+		reqBody = &body
+	}
+
+	req, err := http.NewRequest(c.config.Method, u.String(), reqBody)
+	if err != nil {
+		return "", fmt.Errorf("cannot create request: %w", err)
+	}
+
+	if reqBody != nil {
+		req.Header.Set("Content-Type", c.config.ContentType)
+	}
+	if c.config.AuthValue != "" {
+		req.Header.Set(c.config.AuthHeader, c.config.AuthValue)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	//noinspection ALL
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("cannot read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("unsuccessful status %d: %s", resp.StatusCode, respBody)
+	}
+
+	if c.config.SuccessField != "" {
+		value, err := LookupJSONField(respBody, c.config.SuccessField)
+		if err != nil {
+			return "", fmt.Errorf("cannot parse response %s: %w", respBody, err)
+		}
+
+		if value != c.config.SuccessValue {
+			return "", fmt.Errorf("unsuccessful result %q in field %q", value, c.config.SuccessField)
+		}
+	}
+
+	if c.config.MessageIDField == "" {
+		return "", nil
+	}
+
+	// The message is already accepted, so a missing id is not a reason to fail
+	messageID, _ := LookupJSONField(respBody, c.config.MessageIDField)
+
+	return messageID, nil
+}
+
+// LookupJSONField returns a string representation of the field addressed
+// by a dot-separated path, e.g. "sms.0.status".
+func LookupJSONField(data []byte, path string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return "", err
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return "", fmt.Errorf("field %q is not found", key)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("index %q is out of range", key)
+			}
+			v = node[i]
+		default:
+			return "", fmt.Errorf("field %q is not an object or an array", key)
+		}
+	}
+
+	if v == nil {
+		return "", nil
+	}
+
+	return fmt.Sprint(v), nil
+}
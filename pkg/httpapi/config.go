@@ -0,0 +1,17 @@
+package httpapi
+
+import "time"
+
+// Config describes a provider HTTP API call.
+type Config struct {
+	URL             string        `long:"url" env:"URL" description:"Provider API URL (text/template)"`
+	Method          string        `long:"method" env:"METHOD" description:"HTTP method" default:"POST"`
+	ContentType     string        `long:"content-type" env:"CONTENT_TYPE" description:"Request content type" default:"application/json"`
+	AuthHeader      string        `long:"auth-header" env:"AUTH_HEADER" description:"Authentication header name" default:"Authorization"`
+	AuthValue       string        `long:"auth-value" env:"AUTH_VALUE" description:"Authentication header value"`
+	RequestTemplate string        `long:"request-template" env:"REQUEST_TEMPLATE" description:"Request body (text/template)" default:"{\"phone\":{{json .Phone}},\"text\":{{json .Text}}}"`
+	SuccessField    string        `long:"success-field" env:"SUCCESS_FIELD" description:"Dot-separated path to a response JSON field to check"`
+	SuccessValue    string        `long:"success-value" env:"SUCCESS_VALUE" description:"Expected value of the success field"`
+	MessageIDField  string        `long:"message-id-field" env:"MESSAGE_ID_FIELD" description:"Dot-separated path to a response JSON field with a message id"`
+	Timeout         time.Duration `long:"timeout" env:"TIMEOUT" description:"Request timeout" default:"10s"`
+}
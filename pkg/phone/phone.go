@@ -0,0 +1,152 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+type LineType string
+
+const (
+	LineTypeMobile LineType = "mobile"
+	LineTypeFixed  LineType = "fixed"
+	// Numbering plans like NANP don't tell mobile numbers apart
+	LineTypeFixedOrMobile LineType = "fixed_or_mobile"
+)
+
+var (
+	ErrInvalidNumber = errors.New("invalid phone number")
+	ErrUnknownRegion = errors.New("unknown region")
+)
+
+// Number is a phone number valid by the rules of its region.
+type Number struct {
+	// Region is an ISO 3166-1 alpha-2 code
+	Region      string
+	CountryCode string
+	// National is the significant national number, without a trunk prefix
+	National string
+	LineType LineType
+}
+
+// E164 is the number like +79161234567.
+func (n *Number) E164() string {
+	return "+" + n.CountryCode + n.National
+}
+
+// Digits is the E.164 number without the plus, SMSCs expect it as an international address.
+func (n *Number) Digits() string {
+	return n.CountryCode + n.National
+}
+
+// CanReceiveSMS is false for fixed lines only, the line type of some numbers can't be told.
+func (n *Number) CanReceiveSMS() bool {
+	return n.LineType != LineTypeFixed
+}
+
+// Parse recognizes an international number, with a plus or an international call prefix of the default region,
+// and a national one of the default region, with or without the trunk prefix. Spaces, dashes, dots, slashes
+// and parentheses are ignored, so is the tel: scheme.
+func Parse(input, defaultRegion string) (*Number, error) {
+	s := strings.TrimSpace(input)
+	if len(s) >= 4 && strings.EqualFold(s[:4], "tel:") {
+		s = s[4:]
+	}
+
+	international := strings.HasPrefix(s, "+")
+	if international {
+		s = s[1:]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '/' || r == '(' || r == ')' || r == ' ':
+		default:
+			return nil, ErrInvalidNumber
+		}
+	}
+
+	digits := b.String()
+	if len(digits) < 4 || len(digits) > 17 {
+		return nil, ErrInvalidNumber
+	}
+
+	if international {
+		return parseInternational(digits)
+	}
+
+	def, ok := regionsByCode[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return nil, ErrUnknownRegion
+	}
+
+	for _, prefix := range def.internationalPrefixes {
+		if strings.HasPrefix(digits, prefix) {
+			return parseInternational(digits[len(prefix):])
+		}
+	}
+
+	candidates := []string{digits}
+	if def.trunkPrefix != "" && strings.HasPrefix(digits, def.trunkPrefix) {
+		candidates = append(candidates, digits[len(def.trunkPrefix):])
+	}
+	// Country code without the plus, like 79161234567
+	if strings.HasPrefix(digits, def.countryCode) {
+		candidates = append(candidates, digits[len(def.countryCode):])
+	}
+
+	// The default region goes first, others may share its country code
+	for _, national := range candidates {
+		if def.pattern.MatchString(national) {
+			return def.number(national), nil
+		}
+
+		for _, r := range regionsByCountryCode[def.countryCode] {
+			if r != def && r.pattern.MatchString(national) {
+				return r.number(national), nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidNumber
+}
+
+// Normalize returns the E.164 form of a number of any region.
+func Normalize(input, defaultRegion string) (string, error) {
+	n, err := Parse(input, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+
+	return n.E164(), nil
+}
+
+// parseInternational splits the country code off, country codes are prefix-free.
+func parseInternational(digits string) (*Number, error) {
+	for i := 1; i <= 3 && i < len(digits); i++ {
+		regions, ok := regionsByCountryCode[digits[:i]]
+		if !ok {
+			continue
+		}
+
+		national := digits[i:]
+		for _, r := range regions {
+			if r.pattern.MatchString(national) {
+				return r.number(national), nil
+			}
+
+			// A trunk prefix is often left in brackets, like +44 (0) 20
+			if r.trunkPrefix != "" && strings.HasPrefix(national, r.trunkPrefix) &&
+				r.pattern.MatchString(national[len(r.trunkPrefix):]) {
+				return r.number(national[len(r.trunkPrefix):]), nil
+			}
+		}
+
+		return nil, ErrInvalidNumber
+	}
+
+	return nil, ErrUnknownRegion
+}
@@ -0,0 +1,221 @@
+package phone
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		defaultRegion string
+
+		wantE164     string
+		wantRegion   string
+		wantNational string
+		wantLineType LineType
+		wantErr      error
+	}{
+		{
+			name:          "international with formatting",
+			input:         "+7 (916) 123-45-67",
+			defaultRegion: "RU",
+			wantE164:      "+79161234567",
+			wantRegion:    "RU",
+			wantNational:  "9161234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "national with the trunk prefix",
+			input:         "8 916 123 45 67",
+			defaultRegion: "RU",
+			wantE164:      "+79161234567",
+			wantRegion:    "RU",
+			wantNational:  "9161234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "national without the trunk prefix",
+			input:         "916.123.45.67",
+			defaultRegion: "ru",
+			wantE164:      "+79161234567",
+			wantRegion:    "RU",
+			wantNational:  "9161234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "country code without the plus",
+			input:         "79161234567",
+			defaultRegion: "RU",
+			wantE164:      "+79161234567",
+			wantRegion:    "RU",
+			wantNational:  "9161234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "tel scheme",
+			input:         "tel:+79161234567",
+			defaultRegion: "",
+			wantE164:      "+79161234567",
+			wantRegion:    "RU",
+			wantNational:  "9161234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "fixed line",
+			input:         "+7 495 123-45-67",
+			defaultRegion: "RU",
+			wantE164:      "+74951234567",
+			wantRegion:    "RU",
+			wantNational:  "4951234567",
+			wantLineType:  LineTypeFixed,
+		},
+		{
+			name:          "shared country code",
+			input:         "+7 701 123 45 67",
+			defaultRegion: "RU",
+			wantE164:      "+77011234567",
+			wantRegion:    "KZ",
+			wantNational:  "7011234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "national of another region with the same country code",
+			input:         "8 701 123 45 67",
+			defaultRegion: "RU",
+			wantE164:      "+77011234567",
+			wantRegion:    "KZ",
+			wantNational:  "7011234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "international call prefix",
+			input:         "810 44 20 7946 0958",
+			defaultRegion: "RU",
+			wantE164:      "+442079460958",
+			wantRegion:    "GB",
+			wantNational:  "2079460958",
+			wantLineType:  LineTypeFixed,
+		},
+		{
+			name:          "trunk prefix in brackets",
+			input:         "+44 (0) 7911 123456",
+			defaultRegion: "RU",
+			wantE164:      "+447911123456",
+			wantRegion:    "GB",
+			wantNational:  "7911123456",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "two-digit trunk prefix",
+			input:         "80 29 123 45 67",
+			defaultRegion: "BY",
+			wantE164:      "+375291234567",
+			wantRegion:    "BY",
+			wantNational:  "291234567",
+			wantLineType:  LineTypeMobile,
+		},
+		{
+			name:          "line type can't be told",
+			input:         "+1 212 555 0123",
+			defaultRegion: "RU",
+			wantE164:      "+12125550123",
+			wantRegion:    "US",
+			wantNational:  "2125550123",
+			wantLineType:  LineTypeFixedOrMobile,
+		},
+		{
+			name:          "letters",
+			input:         "+7 916 CALL-ME",
+			defaultRegion: "RU",
+			wantErr:       ErrInvalidNumber,
+		},
+		{
+			name:          "too short",
+			input:         "123",
+			defaultRegion: "RU",
+			wantErr:       ErrInvalidNumber,
+		},
+		{
+			name:          "too long",
+			input:         "+7916123456789012345",
+			defaultRegion: "RU",
+			wantErr:       ErrInvalidNumber,
+		},
+		{
+			name:          "invalid national number",
+			input:         "+7 123 456 78 90",
+			defaultRegion: "RU",
+			wantErr:       ErrInvalidNumber,
+		},
+		{
+			name:          "unknown country code",
+			input:         "+999 123 4567",
+			defaultRegion: "RU",
+			wantErr:       ErrUnknownRegion,
+		},
+		{
+			name:          "national without a default region",
+			input:         "9161234567",
+			defaultRegion: "",
+			wantErr:       ErrUnknownRegion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Parse(tt.input, tt.defaultRegion)
+			if err != tt.wantErr {
+				t.Fatalf("Parse(%q, %q) error = %v, want %v", tt.input, tt.defaultRegion, err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if n.E164() != tt.wantE164 {
+				t.Errorf("E164() = %q, want %q", n.E164(), tt.wantE164)
+			}
+			if n.Region != tt.wantRegion {
+				t.Errorf("Region = %q, want %q", n.Region, tt.wantRegion)
+			}
+			if n.National != tt.wantNational {
+				t.Errorf("National = %q, want %q", n.National, tt.wantNational)
+			}
+			if n.LineType != tt.wantLineType {
+				t.Errorf("LineType = %q, want %q", n.LineType, tt.wantLineType)
+			}
+		})
+	}
+}
+
+func TestNumberCanReceiveSMS(t *testing.T) {
+	tests := []struct {
+		lineType LineType
+		want     bool
+	}{
+		{LineTypeMobile, true},
+		{LineTypeFixedOrMobile, true},
+		{LineTypeFixed, false},
+	}
+
+	for _, tt := range tests {
+		n := &Number{LineType: tt.lineType}
+		if got := n.CanReceiveSMS(); got != tt.want {
+			t.Errorf("CanReceiveSMS() of %s = %t, want %t", tt.lineType, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize("8 (916) 123-45-67", "RU")
+	if err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+
+	if got != "+79161234567" {
+		t.Errorf("Normalize() = %q, want %q", got, "+79161234567")
+	}
+
+	if _, err := Normalize("not a phone", "RU"); err != ErrInvalidNumber {
+		t.Errorf("Normalize() error = %v, want %v", err, ErrInvalidNumber)
+	}
+}
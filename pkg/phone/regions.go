@@ -0,0 +1,151 @@
+package phone
+
+import "regexp"
+
+// region is a numbering plan of a country, patterns match significant national numbers.
+type region struct {
+	code                  string
+	countryCode           string
+	trunkPrefix           string
+	internationalPrefixes []string
+	pattern               *regexp.Regexp
+	// mobile is nil when mobile numbers can't be told apart
+	mobile *regexp.Regexp
+}
+
+func (r *region) number(national string) *Number {
+	n := &Number{
+		Region:      r.code,
+		CountryCode: r.countryCode,
+		National:    national,
+		LineType:    LineTypeFixedOrMobile,
+	}
+
+	if r.mobile != nil {
+		n.LineType = LineTypeFixed
+		if r.mobile.MatchString(national) {
+			n.LineType = LineTypeMobile
+		}
+	}
+
+	return n
+}
+
+// Regions sharing a country code go in order of checking: Kazakhstan takes its ranges of +7 first.
+// NANP numbers are reported as US ones.
+var regions = []*region{
+	{
+		code:                  "KZ",
+		countryCode:           "7",
+		trunkPrefix:           "8",
+		internationalPrefixes: []string{"810", "00"},
+		pattern:               regexp.MustCompile(`^[67]\d{9}$`),
+		mobile:                regexp.MustCompile(`^7(?:0[0-8]|47|5[01]|6[0-4]|7[15-8])\d{7}$`),
+	},
+	{
+		code:                  "RU",
+		countryCode:           "7",
+		trunkPrefix:           "8",
+		internationalPrefixes: []string{"810", "00"},
+		pattern:               regexp.MustCompile(`^[3489]\d{9}$`),
+		mobile:                regexp.MustCompile(`^9\d{9}$`),
+	},
+	{
+		code:                  "BY",
+		countryCode:           "375",
+		trunkPrefix:           "80",
+		internationalPrefixes: []string{"810", "00"},
+		pattern:               regexp.MustCompile(`^(?:1[5-7]|2[1-59]|33|44)\d{7}$`),
+		mobile:                regexp.MustCompile(`^(?:25|29|33|44)\d{7}$`),
+	},
+	{
+		code:                  "UA",
+		countryCode:           "380",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[3-9]\d{8}$`),
+		mobile:                regexp.MustCompile(`^(?:39|50|6[3678]|73|9[1-9])\d{7}$`),
+	},
+	{
+		code:                  "UZ",
+		countryCode:           "998",
+		internationalPrefixes: []string{"00", "810"},
+		pattern:               regexp.MustCompile(`^[2-9]\d{8}$`),
+		mobile:                regexp.MustCompile(`^(?:33|50|55|77|88|9[0-57-9])\d{7}$`),
+	},
+	{
+		code:                  "KG",
+		countryCode:           "996",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[2-9]\d{8}$`),
+		mobile:                regexp.MustCompile(`^(?:2[0-2]|5\d|7\d|88|99)\d{7}$`),
+	},
+	{
+		code:                  "AM",
+		countryCode:           "374",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[1-9]\d{7}$`),
+		mobile:                regexp.MustCompile(`^(?:33|4[1349]|55|77|9[1-9])\d{6}$`),
+	},
+	{
+		code:                  "GE",
+		countryCode:           "995",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[3-7]\d{8}$`),
+		mobile:                regexp.MustCompile(`^5\d{8}$`),
+	},
+	{
+		code:                  "AZ",
+		countryCode:           "994",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[1-9]\d{8}$`),
+		mobile:                regexp.MustCompile(`^(?:10|5[015]|60|7[07]|99)\d{7}$`),
+	},
+	{
+		code:                  "TR",
+		countryCode:           "90",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[2-58]\d{9}$`),
+		mobile:                regexp.MustCompile(`^5\d{9}$`),
+	},
+	{
+		code:                  "DE",
+		countryCode:           "49",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[1-9]\d{5,12}$`),
+		mobile:                regexp.MustCompile(`^1[5-7]\d{8,9}$`),
+	},
+	{
+		code:                  "GB",
+		countryCode:           "44",
+		trunkPrefix:           "0",
+		internationalPrefixes: []string{"00"},
+		pattern:               regexp.MustCompile(`^[1-9]\d{8,9}$`),
+		mobile:                regexp.MustCompile(`^7[1-57-9]\d{8}$`),
+	},
+	{
+		code:                  "US",
+		countryCode:           "1",
+		trunkPrefix:           "1",
+		internationalPrefixes: []string{"011"},
+		pattern:               regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`),
+	},
+}
+
+var (
+	regionsByCode        = make(map[string]*region, len(regions))
+	regionsByCountryCode = make(map[string][]*region, len(regions))
+)
+
+func init() {
+	for _, r := range regions {
+		regionsByCode[r.code] = r
+		regionsByCountryCode[r.countryCode] = append(regionsByCountryCode[r.countryCode], r)
+	}
+}
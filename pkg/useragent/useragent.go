@@ -0,0 +1,107 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// UserAgent is a device and a browser recognized in a User-Agent header.
+// Unrecognized fields are empty.
+type UserAgent struct {
+	Device         string
+	OS             string
+	Browser        string
+	BrowserVersion string
+}
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Order matters: many browsers mention the engines and browsers they are based on.
+var browsers = []rule{
+	{"Yandex Browser", regexp.MustCompile(`YaBrowser/([\d.]+)`)},
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	{"okhttp", regexp.MustCompile(`okhttp/([\d.]+)`)},
+	{"CFNetwork", regexp.MustCompile(`CFNetwork/([\d.]+)`)},
+}
+
+var systems = []rule{
+	{"Windows", regexp.MustCompile(`Windows NT`)},
+	{"iPadOS", regexp.MustCompile(`iPad`)},
+	{"iOS", regexp.MustCompile(`iPhone|iPod|iOS|Darwin`)},
+	{"Android", regexp.MustCompile(`Android`)},
+	{"macOS", regexp.MustCompile(`Mac OS X|Macintosh`)},
+	{"ChromeOS", regexp.MustCompile(`CrOS`)},
+	{"Linux", regexp.MustCompile(`Linux`)},
+}
+
+var botRe = regexp.MustCompile(`(?i)bot|crawler|spider|curl|wget|python|go-http-client`)
+
+// Parse recognizes common browsers and systems, it's good enough to tell devices of a user apart.
+func Parse(s string) *UserAgent {
+	ua := &UserAgent{Device: DeviceUnknown}
+	if s == "" {
+		return ua
+	}
+
+	for _, b := range browsers {
+		if m := b.re.FindStringSubmatch(s); m != nil {
+			ua.Browser, ua.BrowserVersion = b.name, m[1]
+			break
+		}
+	}
+
+	for _, sys := range systems {
+		if sys.re.MatchString(s) {
+			ua.OS = sys.name
+			break
+		}
+	}
+
+	switch {
+	case botRe.MatchString(s):
+		ua.Device = DeviceBot
+	case ua.OS == "iPadOS" || ua.OS == "Android" && !strings.Contains(s, "Mobile"):
+		ua.Device = DeviceTablet
+	case ua.OS == "iOS" || ua.OS == "Android" || strings.Contains(s, "Mobile"):
+		ua.Device = DeviceMobile
+	case ua.OS != "":
+		ua.Device = DeviceDesktop
+	}
+
+	return ua
+}
+
+// String is a human readable name like "Chrome 92 on Android".
+func (ua *UserAgent) String() string {
+	name := ua.Browser
+	if v := strings.SplitN(ua.BrowserVersion, ".", 2)[0]; name != "" && v != "" {
+		name += " " + v
+	}
+
+	switch {
+	case name != "" && ua.OS != "":
+		return name + " on " + ua.OS
+	case name != "":
+		return name
+	case ua.OS != "":
+		return ua.OS
+	default:
+		return "Unknown device"
+	}
+}